@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+/* clientOptions holds the global flags that decide which cluster kubeToggler talks to and as whom. Empty fields fall back to the
+   kubeconfig's own values */
+type clientOptions struct {
+	kubeconfig string
+	context    string
+	cluster    string
+	user       string
+
+	//Client-side rate limit of the requests sent to the API server, see --qps and --burst
+	qps   float64
+	burst int
+}
+
+/* inCluster returns true if kubeToggler is running inside a pod, which kubernetes signals by setting KUBERNETES_SERVICE_HOST */
+func inCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
+}
+
+/* clientConfig builds the clientcmd.ClientConfig described by the options. The kubeconfig is loaded from the --kubeconfig path if
+   one was given, otherwise from the KUBECONFIG environment variable or ~/.kube/config, and --context, --cluster and --user override
+   the matching kubeconfig values */
+func (o clientOptions) clientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: o.context,
+		Context: clientcmdapi.Context{
+			Cluster:  o.cluster,
+			AuthInfo: o.user,
+		},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+/* restConfig returns the *rest.Config used to create the clientset, rate limited to the options' qps and burst. When kubeToggler runs
+   inside a pod and neither --kubeconfig nor KUBECONFIG point at a kubeconfig, the pod's service account is used through
+   rest.InClusterConfig */
+func (o clientOptions) restConfig() (*rest.Config, error) {
+	config, err := (*rest.Config)(nil), error(nil)
+	if o.kubeconfig == "" && os.Getenv(clientcmd.RecommendedConfigPathEnvVar) == "" && inCluster() {
+		config, err = rest.InClusterConfig()
+	} else {
+		config, err = o.clientConfig().ClientConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	//Zero values keep client-go's own defaults
+	if o.qps > 0 {
+		config.QPS = float32(o.qps)
+	}
+	if o.burst > 0 {
+		config.Burst = o.burst
+	}
+	return config, nil
+}
+
+/* initClientSet uses the given clientOptions to find a kubernetes config and create a kubernetes.Interface backed by a
+   kubernetes.Clientset struct (https://pkg.go.dev/k8s.io/client-go/kubernetes#Clientset) */
+func initClientSet(opts clientOptions) (kubernetes.Interface, error) {
+	config, err := opts.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+- name: ci-cluster
+  cluster:
+    server: https://ci.example.com
+users:
+- name: dev-user
+  user:
+    token: dev-token
+- name: ci-user
+  user:
+    token: ci-token
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: dev-namespace
+- name: ci
+  context:
+    cluster: ci-cluster
+    user: ci-user
+`
+
+/* writeKubeconfig writes testKubeconfig into a temporary directory and returns its path */
+func writeKubeconfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+/*
+	Unit test clientOptions.restConfig
+*/
+
+//Tests an explicit --kubeconfig path with no other overrides. Should use the kubeconfig's current context
+func TestRestConfig_ExplicitKubeconfig(t *testing.T) {
+	opts := clientOptions{kubeconfig: writeKubeconfig(t)}
+	config, err := opts.restConfig()
+	if err != nil || config.Host != "https://dev.example.com" || config.BearerToken != "dev-token" {
+		t.Errorf("Returned incorrect config for %+v, got: %+v, error: %v", opts, config, err)
+	}
+}
+
+//Tests --context. Should switch to the cluster and user of that context
+func TestRestConfig_Context(t *testing.T) {
+	opts := clientOptions{kubeconfig: writeKubeconfig(t), context: "ci"}
+	config, err := opts.restConfig()
+	if err != nil || config.Host != "https://ci.example.com" || config.BearerToken != "ci-token" {
+		t.Errorf("Returned incorrect config for %+v, got: %+v, error: %v", opts, config, err)
+	}
+}
+
+//Tests --cluster and --user. Should override the cluster and user of the current context
+func TestRestConfig_ClusterAndUser(t *testing.T) {
+	opts := clientOptions{kubeconfig: writeKubeconfig(t), cluster: "ci-cluster", user: "ci-user"}
+	config, err := opts.restConfig()
+	if err != nil || config.Host != "https://ci.example.com" || config.BearerToken != "ci-token" {
+		t.Errorf("Returned incorrect config for %+v, got: %+v, error: %v", opts, config, err)
+	}
+}
+
+//Tests the KUBECONFIG environment variable. Should be used when --kubeconfig is not given, even inside a pod
+func TestRestConfig_KubeconfigEnv(t *testing.T) {
+	path := writeKubeconfig(t)
+	for key, val := range map[string]string{"KUBECONFIG": path, "KUBERNETES_SERVICE_HOST": "10.0.0.1", "KUBERNETES_SERVICE_PORT": "443"} {
+		old, set := os.LookupEnv(key)
+		os.Setenv(key, val)
+		defer func(key string) {
+			if set {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key)
+	}
+	config, err := clientOptions{}.restConfig()
+	if err != nil || config.Host != "https://dev.example.com" {
+		t.Errorf("Returned incorrect config for KUBECONFIG=%v, got: %+v, error: %v", path, config, err)
+	}
+}
+
+//Tests --qps and --burst. Should set the rate limit of the config, and leave client-go's defaults when unset
+func TestRestConfig_RateLimit(t *testing.T) {
+	opts := clientOptions{kubeconfig: writeKubeconfig(t), qps: 25, burst: 40}
+	config, err := opts.restConfig()
+	if err != nil || config.QPS != 25 || config.Burst != 40 {
+		t.Errorf("Returned incorrect rate limit for %+v, got qps: %v, burst: %v, error: %v", opts, config.QPS, config.Burst, err)
+	}
+	config, err = clientOptions{kubeconfig: opts.kubeconfig}.restConfig()
+	if err != nil || config.QPS != 0 || config.Burst != 0 {
+		t.Errorf("Expected client-go's defaults, got qps: %v, burst: %v, error: %v", config.QPS, config.Burst, err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/* kindCronJob is the kind toggleOn and toggleOff suspend and resume alongside the scaled workloads when --cronjobs is set, and the
+   only kind the suspend and resume commands target. CronJobs have no replicas, so they are switched through spec.suspend instead */
+const kindCronJob = "CronJob"
+
+/* workloadFromCronJob converts a CronJob into a workload. Only its metadata is filled in, since a CronJob has no replicas */
+func workloadFromCronJob(cronJob *batchv1.CronJob) workload {
+	return workload{
+		kind:        kindCronJob,
+		namespace:   cronJob.Namespace,
+		name:        cronJob.Name,
+		labels:      cronJob.Labels,
+		annotations: cronJob.Annotations,
+	}
+}
+
+/* listCronJobs returns the cronjobs in the given namespace whose labels match the given selector */
+func (t *Toggler) listCronJobs(selector labels.Selector, namespace string) ([]batchv1.CronJob, error) {
+	cronJobs := []batchv1.CronJob{}
+	err := listPages(selector, func(options metav1.ListOptions) (string, error) {
+		page, err := t.clientset.BatchV1().CronJobs(namespace).List(context.Background(), options)
+		if err != nil {
+			return "", err
+		}
+		cronJobs = append(cronJobs, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cronJobs, nil
+}
+
+/* SuspendCronJobs finds the cronjobs in the given namespace with the given labels or names and suspends them, so that they stop
+   creating jobs until they are resumed. It returns the names of the suspended cronjobs */
+func (t *Toggler) SuspendCronJobs(selector labels.Selector, names []string, namespace string) ([]string, error) {
+	return t.setCronJobsSuspended(selector, names, true, namespace)
+}
+
+/* ResumeCronJobs finds the cronjobs in the given namespace with the given labels or names and resumes them. It returns the names of
+   the resumed cronjobs */
+func (t *Toggler) ResumeCronJobs(selector labels.Selector, names []string, namespace string) ([]string, error) {
+	return t.setCronJobsSuspended(selector, names, false, namespace)
+}
+
+/* setCronJobsSuspended sets spec.suspend on every cronjob with the given labels or names with a merge patch. Resuming a protected
+   cronjob is always allowed */
+func (t *Toggler) setCronJobsSuspended(selector labels.Selector, names []string, suspend bool, namespace string) ([]string, error) {
+	patch := map[string]interface{}{
+		"spec": map[string]bool{"suspend": suspend},
+	}
+	state := "resumed"
+	if suspend {
+		state = "suspended"
+	}
+	return t.patchWorkloads(kindCronJob, selector, names, types.MergePatchType, patch, state, suspend, namespace)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/* newCronJob returns a cronjob fixture that is not suspended */
+func newCronJob(name string, namespace string, labels map[string]string) *batchv1.CronJob {
+	suspend := false
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *", Suspend: &suspend},
+	}
+}
+
+/* getCronJob fetches a cronjob from the toggler's clientset, failing the test if it does not exist */
+func getCronJob(t *testing.T, toggler *Toggler, name string) *batchv1.CronJob {
+	cronJob, err := toggler.clientset.BatchV1().CronJobs(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Could not get cronjob %v, error: %v", name, err)
+	}
+	return cronJob
+}
+
+/* cronJobObjects returns a deployment and two cronjobs labelled feature=payments, and one cronjob of another feature */
+func cronJobObjects() []runtime.Object {
+	return []runtime.Object{
+		newDeployment("payments-api", namespace, map[string]string{"feature": "payments"}, 2),
+		newCronJob("payments-report", namespace, map[string]string{"feature": "payments"}),
+		newCronJob("payments-cleanup", namespace, map[string]string{"feature": "payments"}),
+		newCronJob("search-reindex", namespace, map[string]string{"feature": "search"}),
+	}
+}
+
+/*
+	Integration test SuspendCronJobs and ResumeCronJobs
+*/
+
+//Tests suspending and resuming by label. Should only switch the cronjobs labelled feature=payments
+func TestSuspendAndResumeCronJobs_Labels(t *testing.T) {
+	toggler, _ := newFakeToggler(cronJobObjects()...)
+	selector := labels.SelectorFromSet(map[string]string{"feature": "payments"})
+	exOut := []string{"payments-cleanup", "payments-report"}
+	out, err := toggler.SuspendCronJobs(selector, nil, namespace)
+	if err != nil || !reflect.DeepEqual(out, exOut) || !*getCronJob(t, toggler, "payments-report").Spec.Suspend || *getCronJob(t, toggler, "search-reindex").Spec.Suspend {
+		t.Errorf("Suspended incorrect cronjobs, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+
+	out, err = toggler.ResumeCronJobs(selector, nil, namespace)
+	if err != nil || !reflect.DeepEqual(out, exOut) || *getCronJob(t, toggler, "payments-report").Spec.Suspend {
+		t.Errorf("Resumed incorrect cronjobs, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests suspending a cronjob that does not exist. Should return an error
+func TestSuspendCronJobs_NonExistentName(t *testing.T) {
+	toggler, _ := newFakeToggler(cronJobObjects()...)
+	out, err := toggler.SuspendCronJobs(nil, []string{"missing"}, namespace)
+	if err == nil {
+		t.Errorf("Expected error for missing cronjob, got: %v, error: %v", out, err)
+	}
+}
+
+//Tests getTargets with --cronjobs. Should target the deployment and the matching cronjobs
+func TestGetTargets_CronJobs(t *testing.T) {
+	toggler, _ := newFakeToggler(cronJobObjects()...)
+	payments := map[string]string{"feature": "payments"}
+	exOut := []namespaceTargets{
+		{namespace: namespace, kind: kindDeployment, names: []string{"payments-api"}, labels: map[string]map[string]string{"payments-api": payments}},
+		{namespace: namespace, kind: kindCronJob, names: []string{"payments-cleanup", "payments-report"}, labels: map[string]map[string]string{"payments-cleanup": payments, "payments-report": payments}},
+	}
+	kinds := []string{kindDeployment, kindStatefulSet, kindCronJob}
+	out, err := toggler.getTargets(kinds, labels.SelectorFromSet(map[string]string{"feature": "payments"}), nil, []string{namespace})
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect targets, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+/*
+	Unit test parseArgs with cronjobs
+*/
+
+//Tests the suspend command and the --cronjobs flag. Should target only cronjobs, or add them to the toggled kinds
+func TestParseArgs_CronJobs(t *testing.T) {
+	osArgs := []string{"kubeToggler", "suspend", "feature=payments", "myNamespace"}
+	args := parseArgs(osArgs)
+	if args.cmd != "suspend" || !reflect.DeepEqual(args.kinds, []string{kindCronJob}) || args.selector.String() != "feature=payments" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+	osArgs = []string{"kubeToggler", "toggleOff", "feature=payments", "myNamespace", "--cronjobs"}
+	args = parseArgs(osArgs)
+	if exKinds := []string{kindDeployment, kindStatefulSet, kindCronJob}; !reflect.DeepEqual(args.kinds, exKinds) {
+		t.Errorf("Returned incorrect kinds for %v, got: %v, want: %v", osArgs, args.kinds, exKinds)
+	}
+}
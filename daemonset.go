@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/* kindDaemonSet is the kind for DaemonSets. They run one pod per matching node instead of a number of replicas, so toggleOff and
+   toggleOn switch them with disabledNodeSelector instead of scaling them */
+const kindDaemonSet = "DaemonSet"
+
+/* disabledNodeSelector is the node selector key toggleOff adds to a DaemonSet's pod template. No node carries the label, so every pod
+   of the DaemonSet is removed, and toggleOn deletes just this key to bring them back */
+const disabledNodeSelector = "kubetoggler.io/disabled"
+
+/* workloadFromDaemonSet converts a DaemonSet into a workload. The number of nodes that should run its pod stands in for the desired
+   replicas and the number of nodes that do run it for the current replicas */
+func workloadFromDaemonSet(daemonSet *appsv1.DaemonSet) workload {
+	return workload{
+		kind:               kindDaemonSet,
+		namespace:          daemonSet.Namespace,
+		name:               daemonSet.Name,
+		labels:             daemonSet.Labels,
+		annotations:        daemonSet.Annotations,
+		selector:           daemonSet.Spec.Selector,
+		generation:         daemonSet.Generation,
+		observedGeneration: daemonSet.Status.ObservedGeneration,
+		specReplicas:       daemonSet.Status.DesiredNumberScheduled,
+		replicas:           daemonSet.Status.CurrentNumberScheduled,
+		updatedReplicas:    daemonSet.Status.UpdatedNumberScheduled,
+		readyReplicas:      daemonSet.Status.NumberReady,
+		availableReplicas:  daemonSet.Status.NumberAvailable,
+	}
+}
+
+/* listDaemonSets returns the daemonsets in the given namespace whose labels match the given selector */
+func (t *Toggler) listDaemonSets(selector labels.Selector, namespace string) ([]appsv1.DaemonSet, error) {
+	daemonSets := []appsv1.DaemonSet{}
+	err := listPages(selector, func(options metav1.ListOptions) (string, error) {
+		page, err := t.clientset.AppsV1().DaemonSets(namespace).List(context.Background(), options)
+		if err != nil {
+			return "", err
+		}
+		daemonSets = append(daemonSets, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return daemonSets, nil
+}
+
+/* DisableDaemonSets finds the daemonsets in the given namespace with the given labels or names and adds disabledNodeSelector to their
+   pod templates, which no node matches, so that all of their pods are removed. It returns the names of the disabled daemonsets */
+func (t *Toggler) DisableDaemonSets(selector labels.Selector, names []string, namespace string) ([]string, error) {
+	value := "true"
+	return t.setDisabledNodeSelector(selector, names, &value, namespace)
+}
+
+/* EnableDaemonSets finds the daemonsets in the given namespace with the given labels or names and removes disabledNodeSelector from
+   their pod templates. Any other node selector the daemonsets had is left as it was. It returns the names of the enabled daemonsets */
+func (t *Toggler) EnableDaemonSets(selector labels.Selector, names []string, namespace string) ([]string, error) {
+	return t.setDisabledNodeSelector(selector, names, nil, namespace)
+}
+
+/* setDisabledNodeSelector sets (or, if value is nil, removes) disabledNodeSelector in the pod template of every daemonset with the
+   given labels or names. The strategic merge patch only touches that one key, so the rest of the node selector is kept */
+func (t *Toggler) setDisabledNodeSelector(selector labels.Selector, names []string, value *string, namespace string) ([]string, error) {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"nodeSelector": map[string]*string{disabledNodeSelector: value},
+				},
+			},
+		},
+	}
+	state := "enabled"
+	if value != nil {
+		state = "disabled"
+	}
+	return t.patchWorkloads(kindDaemonSet, selector, names, types.StrategicMergePatchType, patch, state, value != nil, namespace)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+/* newDaemonSet returns a daemonset fixture scheduled on 'nodes' nodes, with an existing node selector role=logging */
+func newDaemonSet(name string, namespace string, labels map[string]string, nodes int32) *appsv1.DaemonSet {
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+		Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: nodes, CurrentNumberScheduled: nodes, NumberReady: nodes},
+	}
+	daemonSet.Spec.Template.Spec.NodeSelector = map[string]string{"role": "logging"}
+	return daemonSet
+}
+
+/* getDaemonSet fetches a daemonset from the toggler's clientset, failing the test if it does not exist */
+func getDaemonSet(t *testing.T, toggler *Toggler, name string) *appsv1.DaemonSet {
+	daemonSet, err := toggler.clientset.AppsV1().DaemonSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Could not get daemonset %v, error: %v", name, err)
+	}
+	return daemonSet
+}
+
+/*
+	Integration test DisableDaemonSets and EnableDaemonSets
+*/
+
+//Tests disabling and enabling a daemonset. Should add and then remove only disabledNodeSelector, keeping role=logging
+func TestDisableAndEnableDaemonSets_KeepsNodeSelector(t *testing.T) {
+	toggler, _ := newFakeToggler(newDaemonSet("log-shipper", namespace, map[string]string{"feature": "logging"}, 3))
+	selector := labels.SelectorFromSet(map[string]string{"feature": "logging"})
+	_, err := toggler.DisableDaemonSets(selector, nil, namespace)
+	exOff := map[string]string{"role": "logging", disabledNodeSelector: "true"}
+	off := getDaemonSet(t, toggler, "log-shipper").Spec.Template.Spec.NodeSelector
+	if err != nil || !reflect.DeepEqual(off, exOff) {
+		t.Errorf("Disabled incorrectly, got node selector: %v, want: %v, error: %v", off, exOff, err)
+	}
+
+	_, err = toggler.EnableDaemonSets(nil, []string{"log-shipper"}, namespace)
+	exOn := map[string]string{"role": "logging"}
+	on := getDaemonSet(t, toggler, "log-shipper").Spec.Template.Spec.NodeSelector
+	if err != nil || !reflect.DeepEqual(on, exOn) {
+		t.Errorf("Enabled incorrectly, got node selector: %v, want: %v, error: %v", on, exOn, err)
+	}
+}
+
+//Tests getScale on a daemonset. Should report the desired and current number of scheduled pods
+func TestGetScales_DaemonSet(t *testing.T) {
+	toggler, _ := newFakeToggler(newDaemonSet("log-shipper", namespace, nil, 3))
+	out, err := toggler.GetScales(kindDaemonSet, nil, []string{"log-shipper"}, namespace)
+	if exOut := "3/3"; err != nil || scaleOf(out, "log-shipper") != exOut {
+		t.Errorf("Returned incorrect scales, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests setScale on a daemonset. Should return an error since daemonsets have no replicas
+func TestSetScales_DaemonSet(t *testing.T) {
+	toggler, _ := newFakeToggler(newDaemonSet("log-shipper", namespace, nil, 3))
+	out, err := toggler.SetScales(kindDaemonSet, nil, []string{"log-shipper"}, 2, namespace)
+	if err == nil {
+		t.Errorf("Expected error for scaling a daemonset, got: %v, error: %v", out, err)
+	}
+}
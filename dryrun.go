@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/* The --dry-run modes of the mutating commands. dryRunClient reads the cluster but never writes to it, dryRunServer sends every write
+   with DryRun set to All so the API server validates it and runs admission webhooks without persisting anything */
+const (
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
+/* checkDryRun returns the --dry-run mode a flag value stands for, with "" meaning the commands change the cluster */
+func checkDryRun(dryRunArg string) (string, error) {
+	switch dryRunArg {
+	case "", dryRunNone:
+		return "", nil
+	case dryRunClient, dryRunServer:
+		return dryRunArg, nil
+	}
+	return "", fmt.Errorf("error: unknown dry run mode %q, must be none, client or server", dryRunArg)
+}
+
+/* dryRunOptions returns the DryRun field sent with every write. It is only set in dryRunServer mode */
+func (t *Toggler) dryRunOptions() []string {
+	if t.dryRun == dryRunServer {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+/* dryRunSuffix returns the note appended to every change the Toggler reports while in a dry run mode */
+func (t *Toggler) dryRunSuffix() string {
+	switch t.dryRun {
+	case dryRunClient:
+		return " (dry run)"
+	case dryRunServer:
+		return " (server dry run)"
+	}
+	return ""
+}
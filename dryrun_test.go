@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+/* writeActions returns the verbs of every update and patch the fake clientset received */
+func writeActions(clientset *fake.Clientset) []string {
+	writes := []string{}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "update" || action.GetVerb() == "patch" {
+			writes = append(writes, action.GetVerb()+" "+action.GetResource().Resource+"/"+action.GetSubresource())
+		}
+	}
+	return writes
+}
+
+/*
+	Integration test --dry-run=client
+*/
+
+//Tests setScale in client dry run. Should print the before and after replicas and send no writes
+func TestDryRunClient_SetScale(t *testing.T) {
+	toggler, clientset, out := newBufferedToggler(newDeployment("testconnector-connector", namespace, nil, 2))
+	toggler.dryRun = dryRunClient
+	scales, err := toggler.SetScales(kindDeployment, nil, []string{"testconnector-connector"}, 5, namespace)
+	exOut := "testconnector-connector: 2 -> 5 (dry run)\n"
+	if err != nil || out.String() != exOut || len(scales) != 1 || scales[0].Spec.Replicas != 5 {
+		t.Errorf("Printed incorrect dry run, got: %q, want: %q, scales: %v, error: %v", out.String(), exOut, scales, err)
+	}
+	if writes := writeActions(clientset); len(writes) != 0 || *getDeployment(t, toggler, "testconnector-connector").Spec.Replicas != 2 {
+		t.Errorf("Expected no writes in client dry run, got: %v", writes)
+	}
+}
+
+//Tests toggleOff in client dry run. Should neither scale the deployment nor record its replicas
+func TestDryRunClient_ToggleOff(t *testing.T) {
+	toggler, clientset, out := newBufferedToggler(newDeployment("testconnector-connector", namespace, nil, 2))
+	toggler.dryRun = dryRunClient
+	_, err := toggler.ToggleOff(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	exOut := "testconnector-connector: 2 -> 0 (dry run)\n"
+	deployment := getDeployment(t, toggler, "testconnector-connector")
+	if _, recorded := deployment.Annotations[previousReplicasAnnotation]; err != nil || out.String() != exOut || recorded || len(writeActions(clientset)) != 0 {
+		t.Errorf("Toggled off in dry run, got: %q, want: %q, annotations: %v, writes: %v, error: %v", out.String(), exOut, deployment.Annotations, writeActions(clientset), err)
+	}
+}
+
+//Tests a bounce reset in client dry run. Should report the scale down and back up without waiting for anything
+func TestDryRunClient_ResetBounce(t *testing.T) {
+	toggler, clientset, out := newBufferedToggler(newDeployment("testconnector-connector", namespace, nil, 2))
+	toggler.dryRun = dryRunClient
+	err := toggler.ResetWorkloads(kindDeployment, nil, []string{"testconnector-connector"}, resetModeBounce, time.Millisecond, namespace)
+	exOut := "testconnector-connector: 2 -> 0 (dry run)\ntestconnector-connector: 0 -> 2 (dry run)\n"
+	if err != nil || out.String() != exOut || len(writeActions(clientset)) != 0 {
+		t.Errorf("Printed incorrect dry run, got: %q, want: %q, writes: %v, error: %v", out.String(), exOut, writeActions(clientset), err)
+	}
+}
+
+/*
+	Unit test --dry-run=server
+*/
+
+//Tests the options sent in server dry run. Should set DryRun to All, and leave it unset otherwise
+func TestDryRunOptions(t *testing.T) {
+	toggler, _, _ := newBufferedToggler(newDeployment("testconnector-connector", namespace, nil, 2))
+	toggler.dryRun = dryRunServer
+	if out := toggler.dryRunOptions(); !reflect.DeepEqual(out, []string{metav1.DryRunAll}) || toggler.dryRunSuffix() != " (server dry run)" {
+		t.Errorf("Returned incorrect dry run options, got: %v, suffix: %q", out, toggler.dryRunSuffix())
+	}
+	toggler.dryRun = ""
+	if out := toggler.dryRunOptions(); out != nil || toggler.dryRunSuffix() != "" {
+		t.Errorf("Expected no dry run options, got: %v, suffix: %q", out, toggler.dryRunSuffix())
+	}
+}
+
+//Tests the --dry-run flag. Should accept none, client and server and reject anything else
+func TestParseArgs_DryRun(t *testing.T) {
+	osArgs := []string{"kubeToggler", "toggleOff", "app=web", "myNamespace", "--dry-run=server"}
+	if args := parseArgs(osArgs); args.dryRun != dryRunServer {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+	if mode, err := checkDryRun("none"); mode != "" || err != nil {
+		t.Errorf("Returned incorrect dry run mode for none, got: %q, error: %v", mode, err)
+	}
+	if _, err := checkDryRun("true"); err == nil {
+		t.Errorf("Expected error for dry run mode true")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* contextSeparator is printed between two groups of matching lines and their context that aren't next to each other, like grep does */
+const contextSeparator = "--"
+
+/* logFilter picks the log lines getPodLogs prints. A line is printed if it matches grep and the JSON field match, either of which may
+   be unset, along with up to 'before' lines before and 'after' lines after every matching line. A nil logFilter prints every line */
+type logFilter struct {
+	grep   *regexp.Regexp
+	field  []string
+	value  string
+	before int
+	after  int
+}
+
+/* newLogFilter builds the logFilter of the --grep, --json-filter and context flags. It returns nil if neither --grep nor
+   --json-filter is set, since there is nothing to filter then */
+func newLogFilter(grepArg string, jsonFilterArg string, before int, after int) (*logFilter, error) {
+	if before < 0 || after < 0 {
+		return nil, errors.New("error: the number of context lines must not be negative")
+	}
+	if grepArg == "" && jsonFilterArg == "" {
+		if before > 0 || after > 0 {
+			return nil, errors.New("error: context lines are only printed around the lines matching --grep or --json-filter")
+		}
+		return nil, nil
+	}
+
+	f := &logFilter{before: before, after: after}
+	if grepArg != "" {
+		grep, err := regexp.Compile(grepArg)
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid --grep expression: %v", err)
+		}
+		f.grep = grep
+	}
+	if jsonFilterArg != "" {
+		parts := strings.SplitN(jsonFilterArg, "=", 2)
+		path := strings.TrimPrefix(parts[0], ".")
+		if len(parts) != 2 || path == "" {
+			return nil, fmt.Errorf("error: --json-filter %q must look like FIELD=VALUE, e.g. level=error or .http.status=500", jsonFilterArg)
+		}
+		f.field = strings.Split(path, ".")
+		f.value = parts[1]
+	}
+	return f, nil
+}
+
+/* matches returns true if the line matches the filter. The timestamp --timestamps puts in front of a line is ignored */
+func (f *logFilter) matches(line string) bool {
+	if _, text, ok := splitTimestamp(line); ok {
+		line = text
+	}
+	if f.grep != nil && !f.grep.MatchString(line) {
+		return false
+	}
+	return f.field == nil || f.matchesField(line)
+}
+
+/* matchesField returns true if the line is a JSON object whose field at the filter's dotted path holds the filter's value. Strings
+   are compared as they are, any other value by its JSON encoding, so that level=error, http.status=500 and retry=true all match.
+   A number in the path indexes an array. A line that isn't JSON never matches */
+func (f *logFilter) matchesField(line string) bool {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	value := interface{}(nil)
+	if err := decoder.Decode(&value); err != nil {
+		return false
+	}
+	for _, key := range f.field {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return false
+			}
+			value = v[i]
+		default:
+			return false
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v == f.value
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	encoded, err := json.Marshal(value)
+	return err == nil && bytes.Equal(encoded, []byte(f.value))
+}
+
+/* contextFilter applies a logFilter to the lines of a single log in the order they were written. It holds on to at most 'before'
+   lines, so that it can filter logs of any size */
+type contextFilter struct {
+	filter  *logFilter
+	pending []string
+	after   int
+	printed bool
+	skipped bool
+}
+
+/* newContext returns the contextFilter that filters a single log, or nil for a nil logFilter */
+func (f *logFilter) newContext() *contextFilter {
+	if f == nil {
+		return nil
+	}
+	return &contextFilter{filter: f}
+}
+
+/* next takes the next line of the log and returns the lines to print for it: nothing, the line itself, or the line preceded by the
+   context lines before it and, if lines were left out since the last printed one, a contextSeparator. A nil contextFilter returns
+   every line */
+func (c *contextFilter) next(line string) []string {
+	if c == nil {
+		return []string{line}
+	}
+
+	lines := []string{}
+	switch {
+	case c.filter.matches(line):
+		if c.skipped && c.printed {
+			lines = append(lines, contextSeparator)
+		}
+		lines = append(append(lines, c.pending...), line)
+		c.pending = nil
+		c.after = c.filter.after
+	case c.after > 0:
+		lines = append(lines, line)
+		c.after--
+	default:
+		if c.filter.before == 0 {
+			c.skipped = true
+			return lines
+		}
+		if len(c.pending) == c.filter.before {
+			c.pending = c.pending[1:]
+			c.skipped = true
+		}
+		c.pending = append(c.pending, line)
+		return lines
+	}
+	c.printed = true
+	c.skipped = false
+	return lines
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* mustLogFilter returns the logFilter of the given flags, failing the test if they are invalid */
+func mustLogFilter(t *testing.T, grepArg string, jsonFilterArg string, before int, after int) *logFilter {
+	f, err := newLogFilter(grepArg, jsonFilterArg, before, after)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: %v", err)
+	}
+	return f
+}
+
+/* filterLines runs every line of the log through a single contextFilter and returns the lines it prints */
+func filterLines(f *logFilter, log string) []string {
+	c := f.newContext()
+	printed := []string{}
+	for _, line := range strings.Split(log, "\n") {
+		printed = append(printed, c.next(line)...)
+	}
+	return printed
+}
+
+/*
+	Unit test newLogFilter
+*/
+
+//Tests invalid filter flags. Should return an error for each
+func TestNewLogFilter_Invalid(t *testing.T) {
+	cases := []struct {
+		grepArg       string
+		jsonFilterArg string
+		before        int
+		after         int
+	}{
+		{"(unclosed", "", 0, 0},
+		{"", "level", 0, 0},
+		{"", "=error", 0, 0},
+		{"error", "", -1, 0},
+		{"", "", 2, 0},
+	}
+	for _, c := range cases {
+		if _, err := newLogFilter(c.grepArg, c.jsonFilterArg, c.before, c.after); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+}
+
+//Tests no filter flags. Should return a nil filter, which prints every line
+func TestNewLogFilter_None(t *testing.T) {
+	f := mustLogFilter(t, "", "", 0, 0)
+	if out := filterLines(f, "a\nb"); f != nil || strings.Join(out, ",") != "a,b" {
+		t.Errorf("Returned incorrect filter, got: %v printing %v, want: nil printing [a b]", f, out)
+	}
+}
+
+/*
+	Unit test logFilter.matches
+*/
+
+//Tests --grep. Should match the expression against the line without the timestamp in front of it
+func TestLogFilter_Grep(t *testing.T) {
+	f := mustLogFilter(t, "^(WARN|ERROR) ", "", 0, 0)
+	cases := map[string]bool{
+		"ERROR connection refused":                     true,
+		"2021-03-01T12:00:00Z WARN slow query":         true,
+		"INFO ERROR is only mentioned":                 false,
+		"2021-03-01T12:00:00Z INFO everything is fine": false,
+	}
+	for line, exOut := range cases {
+		if out := f.matches(line); out != exOut {
+			t.Errorf("Returned incorrect match for %q, got: %v, want: %v", line, out, exOut)
+		}
+	}
+}
+
+//Tests --json-filter with strings, numbers, booleans, nested fields and array indexes
+func TestLogFilter_JSON(t *testing.T) {
+	cases := []struct {
+		jsonFilterArg string
+		line          string
+		exOut         bool
+	}{
+		{"level=error", `{"level":"error","msg":"boom"}`, true},
+		{"level=error", `{"level":"info","msg":"error"}`, false},
+		{".http.status=500", `{"http":{"status":500}}`, true},
+		{"http.status=500", `{"http":{"status":"500"}}`, true},
+		{"http.status=500", `{"http":{"status":502}}`, false},
+		{"retry=true", `{"retry":true}`, true},
+		{"spans.1.name=db", `{"spans":[{"name":"http"},{"name":"db"}]}`, true},
+		{"spans.2.name=db", `{"spans":[{"name":"http"},{"name":"db"}]}`, false},
+		{"http=500", `{"http":{"status":500}}`, false},
+		{"level=error", `level=error msg=boom`, false},
+		{"level=error", `2021-03-01T12:00:00Z {"level":"error"}`, true},
+	}
+	for _, c := range cases {
+		f := mustLogFilter(t, "", c.jsonFilterArg, 0, 0)
+		if out := f.matches(c.line); out != c.exOut {
+			t.Errorf("Returned incorrect match for %s on %s, got: %v, want: %v", c.jsonFilterArg, c.line, out, c.exOut)
+		}
+	}
+}
+
+//Tests --grep together with --json-filter. Should only match lines that match both
+func TestLogFilter_GrepAndJSON(t *testing.T) {
+	f := mustLogFilter(t, "timeout", "level=error", 0, 0)
+	if !f.matches(`{"level":"error","msg":"timeout"}`) || f.matches(`{"level":"error","msg":"refused"}`) || f.matches(`{"level":"info","msg":"timeout"}`) {
+		t.Errorf("Returned incorrect matches for --grep timeout --json-filter level=error")
+	}
+}
+
+/*
+	Unit test contextFilter
+*/
+
+//Tests context lines before and after matches. Should print each line once and separate groups that aren't next to each other
+func TestContextFilter(t *testing.T) {
+	log := "1\n2\n3\nERROR a\n5\n6\n7\n8\nERROR b\n10\nERROR c\n12"
+	cases := []struct {
+		before int
+		after  int
+		exOut  []string
+	}{
+		{0, 0, []string{"ERROR a", "--", "ERROR b", "--", "ERROR c"}},
+		{1, 1, []string{"3", "ERROR a", "5", "--", "8", "ERROR b", "10", "ERROR c", "12"}},
+		{3, 0, []string{"1", "2", "3", "ERROR a", "--", "6", "7", "8", "ERROR b", "10", "ERROR c"}},
+		{0, 4, []string{"ERROR a", "5", "6", "7", "8", "ERROR b", "10", "ERROR c", "12"}},
+	}
+	for _, c := range cases {
+		f := mustLogFilter(t, "ERROR", "", c.before, c.after)
+		if out := filterLines(f, log); strings.Join(out, ",") != strings.Join(c.exOut, ",") {
+			t.Errorf("Printed incorrect lines with %d before and %d after, got: %v, want: %v", c.before, c.after, out, c.exOut)
+		}
+	}
+}
+
+/*
+	Integration test filtering getPodLogs
+*/
+
+//Tests filtering the logs of a deployment against the fake clientset, which answers every log request with "fake logs"
+func TestPodLogResults_Filter(t *testing.T) {
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newRunningPod("testconnector-a", "testconnector-connector", "app"),
+	)
+	cases := map[string]string{"fake": "fake logs\n", "real": ""}
+	for grepArg, exOut := range cases {
+		opts := logOptions{tail: -1, filter: mustLogFilter(t, grepArg, "", 0, 0)}
+		results, err := toggler.podLogResults("testconnector-connector", namespace, opts)
+		if err != nil || len(results) != 1 || results[0].Log != exOut {
+			t.Errorf("Returned incorrect logs for --grep %s, got: %+v, want: %q, error: %v", grepArg, results, exOut, err)
+		}
+	}
+}
+
+//Tests following filtered logs. Should only stream the lines that match
+func TestFollowPodLogs_Filter(t *testing.T) {
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newRunningPod("testconnector-a", "testconnector-connector", "app"),
+		newRunningPod("testconnector-b", "testconnector-connector", "app"),
+	)
+	out := &syncBuffer{}
+	toggler.out = out
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := toggler.FollowPodLogs(ctx, "testconnector-connector", namespace, logOptions{tail: -1, filter: mustLogFilter(t, "real", "", 0, 0)})
+	if err != nil || out.buf.String() != "" {
+		t.Errorf("Followed incorrect logs, got: %q, want: none, error: %v", out.buf.String(), err)
+	}
+}
+
+//Tests merging filtered logs. Should keep the context lines of each log with the line that matched
+func TestMergeLogs_Filter(t *testing.T) {
+	f := mustLogFilter(t, "", "level=error", 1, 0)
+	sources := []*logSource{
+		newLogSource("web-a", strings.NewReader("2021-03-01T12:00:01Z {\"level\":\"info\",\"msg\":\"a1\"}\n2021-03-01T12:00:04Z {\"level\":\"error\",\"msg\":\"a2\"}\n")),
+		newLogSource("web-b", strings.NewReader("2021-03-01T12:00:02Z {\"level\":\"error\",\"msg\":\"b1\"}\n2021-03-01T12:00:03Z {\"level\":\"info\",\"msg\":\"b2\"}\n")),
+	}
+	for _, s := range sources {
+		s.filter = f.newContext()
+	}
+	out := new(bytes.Buffer)
+	err := mergeLogs(out, sources, false)
+	exOut := "[web-b] {\"level\":\"error\",\"msg\":\"b1\"}\n[web-a] {\"level\":\"info\",\"msg\":\"a1\"}\n[web-a] {\"level\":\"error\",\"msg\":\"a2\"}\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Merged incorrectly, got:\n%v\nwant:\n%v\nerror: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests the filter flags. Should build the kubeCmd's filter, with --context-lines standing in for an unset --before-context
+func TestParseArgs_Filter(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getPodLogs", "--grep", "timeout", "--json-filter", ".level=error", "-C", "2", "--after-context", "5", "myConnector", "myNamespace"}
+	f := parseArgs(osArgs).logs.filter
+	if f == nil || f.grep.String() != "timeout" || strings.Join(f.field, ".") != "level" || f.value != "error" || f.before != 2 || f.after != 5 {
+		t.Errorf("Returned incorrect filter for %v, got: %+v", osArgs, f)
+	}
+}
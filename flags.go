@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* newFlagSet returns a flag.FlagSet that writes every optional --flag argument into the matching field of args */
+func newFlagSet(args *kubeCmd) *flag.FlagSet {
+	fs := flag.NewFlagSet("kubeToggler", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	//Global flags that choose the cluster, mirroring kubectl's flags of the same name
+	fs.StringVar(&args.client.kubeconfig, "kubeconfig", "", "path to the kubeconfig file to use")
+	fs.StringVar(&args.client.context, "context", "", "name of the kubeconfig context to use")
+	fs.StringVar(&args.client.cluster, "cluster", "", "name of the kubeconfig cluster to use")
+	fs.StringVar(&args.client.user, "user", "", "name of the kubeconfig user to use")
+	fs.StringVar(&args.namespaceArg, "namespace", "", "namespace (or comma-separated namespaces) to target, replaces the trailing NAMESPACE argument")
+	fs.StringVar(&args.namespaceArg, "n", "", "shorthand for --namespace")
+	fs.BoolVar(&args.allNamespaces, "all-namespaces", false, "target every namespace, replaces the trailing NAMESPACE argument")
+	fs.BoolVar(&args.allNamespaces, "A", false, "shorthand for --all-namespaces")
+	fs.StringVar(&args.namespaceLabelArg, "namespace-selector", "", "target the namespaces matching this label selector, replaces the trailing NAMESPACE argument")
+	fs.Float64Var(&args.client.qps, "qps", defaultQPS, "maximum requests per second sent to the API server")
+	fs.IntVar(&args.client.burst, "burst", defaultBurst, "maximum burst of requests sent to the API server above --qps")
+
+	//Command flags
+	fs.StringVar(&args.labelArg, "selector", "", "label selector to target, e.g. 'tier in (web,api),!canary'")
+	fs.StringVar(&args.labelArg, "l", "", "shorthand for --selector")
+	fs.StringVar(&args.kindArg, "kind", kindAuto, "kind of workload the scale commands target: deployment, statefulset, daemonset or auto (deployments and statefulsets)")
+	fs.StringVar(&args.resetMode, "mode", resetModeRolling, "how reset restarts deployments, either rolling or bounce")
+	fs.BoolVar(&args.wait, "wait", false, "wait until scaled deployments are ready, or have no pods left when scaled to 0")
+	fs.DurationVar(&args.timeout, "timeout", 5*time.Minute, "how long to wait for deployments to become ready")
+	fs.StringVar(&args.output, "output", "", "output format of the read commands: json, yaml, table, name, go-template=TEMPLATE or jsonpath=TEMPLATE")
+	fs.StringVar(&args.output, "o", "", "shorthand for --output")
+	fs.StringVar(&args.sortBy, "sort-by", "", "field to sort read command output by after namespace and name, e.g. scale, age or restarts")
+	fs.StringVar(&args.dryRunArg, "dry-run", "", "none, client (print the changes without making them) or server (send them as a server-side dry run)")
+	fs.BoolVar(&args.yes, "yes", false, "change the targets without asking for confirmation")
+	fs.BoolVar(&args.yes, "y", false, "shorthand for --yes")
+	fs.IntVar(&args.maxTargets, "max-targets", 0, "abort if a mutating command matches more than this many objects (0 for no limit)")
+	fs.IntVar(&args.confirmThreshold, "confirm-threshold", defaultConfirmThreshold, "ask for confirmation when a mutating command matches more than this many objects")
+	fs.StringVar(&args.protectedNamespacesArg, "protected-namespaces", defaultProtectedNamespaces, "comma-separated namespace patterns where every change asks for confirmation")
+	fs.BoolVar(&args.force, "force", false, "also change workloads annotated kubetoggler.io/protected=true, recording an event on each one")
+	fs.IntVar(&args.concurrency, "concurrency", defaultConcurrency, "number of objects to get, scale or read logs from at the same time")
+	fs.BoolVar(&args.follow, "follow", false, "stream the logs of every pod of the deployment, including new ones, until interrupted")
+	fs.BoolVar(&args.follow, "f", false, "shorthand for --follow")
+	fs.BoolVar(&args.merge, "merge", false, "print the logs of every pod as a single stream, in the order the lines were written")
+	fs.DurationVar(&args.logs.since, "since", 0, "only read the log lines newer than this duration, e.g. 10m")
+	fs.StringVar(&args.sinceTimeArg, "since-time", "", "only read the log lines newer than this RFC3339 time")
+	fs.Int64Var(&args.logs.tail, "tail", -1, "only read this many lines from the end of each log (-1 for the whole log)")
+	fs.BoolVar(&args.logs.timestamps, "timestamps", false, "prefix every log line with the time it was written")
+	fs.BoolVar(&args.logs.previous, "previous", false, "read the log of the previous instance of each container, e.g. the one that crashed")
+	fs.BoolVar(&args.logs.previous, "p", false, "shorthand for --previous")
+	fs.StringVar(&args.logs.container, "container", "", "only read the logs of the container with this name")
+	fs.StringVar(&args.logs.container, "c", "", "shorthand for --container")
+	fs.BoolVar(&args.logs.allContainers, "all-containers", false, "also read the logs of the init containers")
+	fs.StringVar(&args.grepArg, "grep", "", "only print the log lines matching this regular expression")
+	fs.StringVar(&args.jsonFilterArg, "json-filter", "", "only print the JSON log lines whose field holds a value, e.g. level=error or .http.status=500")
+	fs.IntVar(&args.beforeContext, "before-context", 0, "also print this many lines before every line matching --grep or --json-filter")
+	fs.IntVar(&args.beforeContext, "B", 0, "shorthand for --before-context")
+	fs.IntVar(&args.afterContext, "after-context", 0, "also print this many lines after every line matching --grep or --json-filter")
+	fs.IntVar(&args.contextLines, "context-lines", 0, "also print this many lines before and after every line matching --grep or --json-filter")
+	fs.IntVar(&args.contextLines, "C", 0, "shorthand for --context-lines")
+	fs.BoolVar(&args.verbose, "verbose", false, "print every write that is retried after a conflict or a transient API error")
+	fs.BoolVar(&args.cronJobs, "cronjobs", false, "make toggleOff and toggleOn also suspend and resume the cronjobs they target")
+
+	return fs
+}
+
+/* parseFlags removes every --flag argument from osArgs, wherever it appears, and stores its value in args. It returns the remaining
+   positional arguments in their original order */
+func parseFlags(osArgs []string, args *kubeCmd) ([]string, error) {
+	fs := newFlagSet(args)
+	positional := []string{}
+	for {
+		if err := fs.Parse(osArgs); err != nil {
+			return nil, err
+		}
+
+		//flag stops at the first positional argument, so it is set aside and parsing resumes after it
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		osArgs = fs.Args()[1:]
+	}
+}
+
+/* checkNamespaceFlags returns an error if more than one namespace flag is given, or if one is given along with what looks like the
+   trailing NAMESPACE argument, which would otherwise be taken for one more target */
+func checkNamespaceFlags(positional []string, args kubeCmd) error {
+	flags := 0
+	for _, set := range []bool{args.namespaceArg != "", args.allNamespaces, args.namespaceLabelArg != ""} {
+		if set {
+			flags++
+		}
+	}
+	if flags > 1 {
+		return errors.New("error: only one of --namespace, --all-namespaces and --namespace-selector can be given")
+	}
+	if flags == 0 || len(positional) < 3 {
+		return nil
+	}
+
+	//The last argument is a namespace if it is the one given with --namespace, or a name after labels, which can't be a target.
+	//A number after labels is setScale's SCALE
+	last := positional[len(positional)-1]
+	isNamespace := false
+	for _, ns := range strings.Split(args.namespaceArg, ",") {
+		isNamespace = isNamespace || strings.TrimSpace(ns) == last
+	}
+	if _, err := strconv.Atoi(last); err != nil && !isLabelArg(last) {
+		for _, arg := range positional[1 : len(positional)-1] {
+			isNamespace = isNamespace || isLabelArg(arg)
+		}
+	}
+	if isNamespace {
+		return fmt.Errorf("error: %q looks like the NAMESPACE argument, which is left off when the namespace is given with a flag", last)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+	Unit test parseFlags
+*/
+
+//Tests flags placed before, between and after positional arguments. Should strip every flag and keep the positionals in order
+func TestParseFlags_Interleaved(t *testing.T) {
+	args := kubeCmd{}
+	osArgs := []string{"--context", "dev", "getScale", "--kubeconfig=/tmp/config", "app=web", "--user", "admin", "myNamespace"}
+	exOut := []string{"getScale", "app=web", "myNamespace"}
+	out, err := parseFlags(osArgs, &args)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect positionals for %v, got: %v, want: %v, error: %v", osArgs, out, exOut, err)
+	}
+	exClient := clientOptions{kubeconfig: "/tmp/config", context: "dev", user: "admin", qps: defaultQPS, burst: defaultBurst}
+	if args.client != exClient {
+		t.Errorf("Returned incorrect client options for %v, got: %+v, want: %+v", osArgs, args.client, exClient)
+	}
+}
+
+//Tests an unknown flag. Should return an error
+func TestParseFlags_UnknownFlag(t *testing.T) {
+	args := kubeCmd{}
+	osArgs := []string{"getScale", "--bogus", "app=web", "myNamespace"}
+	out, err := parseFlags(osArgs, &args)
+	if err == nil {
+		t.Errorf("Expected error for %v, got: %v, error: %v", osArgs, out, err)
+	}
+}
+
+/*
+	Unit test parseArgs
+*/
+
+//Tests the -n shorthand. Should be used as the namespace in place of the trailing NAMESPACE argument
+func TestParseArgs_NamespaceFlag(t *testing.T) {
+	osArgs := []string{"kubeToggler", "-n", "myNamespace", "getScale", "app=web"}
+	args := parseArgs(osArgs)
+	if args.cmd != "getScale" || args.namespace != "myNamespace" || args.selector.String() != "app=web" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests setScale with a trailing NAMESPACE argument and a --kubeconfig flag
+func TestParseArgs_SetScaleWithFlag(t *testing.T) {
+	osArgs := []string{"kubeToggler", "setScale", "myConnector", "3", "myNamespace", "--kubeconfig", "/tmp/config"}
+	args := parseArgs(osArgs)
+	if args.cmd != "setScale" || args.scale != 3 || args.namespace != "myNamespace" || !reflect.DeepEqual(args.names, []string{"myConnector"}) || args.client.kubeconfig != "/tmp/config" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests the -l shorthand with a set-based selector and no positional target arguments
+func TestParseArgs_SelectorFlag(t *testing.T) {
+	osArgs := []string{"kubeToggler", "toggleOff", "-l", "tier in (web,api),!canary", "myNamespace"}
+	args := parseArgs(osArgs)
+	if args.cmd != "toggleOff" || args.namespace != "myNamespace" || args.names != nil || args.selector.String() != "!canary,tier in (api,web)" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests a positional selector expression combined with the --selector flag on getName
+func TestParseArgs_SelectorFlagAndLabels(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getName", "env!=prod", "--selector", "team", "myNamespace"}
+	args := parseArgs(osArgs)
+	if args.cmd != "getName" || args.namespace != "myNamespace" || args.selector.String() != "env!=prod,team" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests --all-namespaces. Should stand in for the trailing NAMESPACE argument and make the command multi-namespace
+func TestParseArgs_AllNamespaces(t *testing.T) {
+	osArgs := []string{"kubeToggler", "toggleOff", "feature=payments", "-A"}
+	args := parseArgs(osArgs)
+	if args.cmd != "toggleOff" || args.namespace != "" || !args.multiNamespace() || args.selector.String() != "feature=payments" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests --namespace-selector. Should be parsed into a namespace selector
+func TestParseArgs_NamespaceSelector(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getScale", "feature=payments", "--namespace-selector", "team=payments"}
+	args := parseArgs(osArgs)
+	if args.cmd != "getScale" || args.namespace != "" || args.namespaceSelector.String() != "team=payments" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests --kind. Should narrow the scale commands to statefulsets but leave getName on deployments
+func TestParseArgs_KindFlag(t *testing.T) {
+	osArgs := []string{"kubeToggler", "toggleOff", "kafka", "--kind", "statefulset", "myNamespace"}
+	args := parseArgs(osArgs)
+	if args.cmd != "toggleOff" || !reflect.DeepEqual(args.kinds, []string{kindStatefulSet}) || !reflect.DeepEqual(args.names, []string{"kafka"}) {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+	osArgs = []string{"kubeToggler", "getName", "app=kafka", "myNamespace"}
+	args = parseArgs(osArgs)
+	if !reflect.DeepEqual(args.kinds, []string{kindDeployment}) {
+		t.Errorf("Returned incorrect kinds for %v, got: %v", osArgs, args.kinds)
+	}
+}
+
+//Tests the -o shorthand. Should store the output format
+func TestParseArgs_OutputFlag(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getScale", "app=web", "myNamespace", "-o", "json"}
+	args := parseArgs(osArgs)
+	if args.cmd != "getScale" || args.output != outputJSON {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+/*
+	Unit test checkNamespaceFlags
+*/
+
+//Tests namespace flags together with a trailing NAMESPACE argument. Should reject the argument instead of taking it for a target
+func TestCheckNamespaceFlags(t *testing.T) {
+	cases := []struct {
+		positional []string
+		args       kubeCmd
+		exErr      bool
+	}{
+		{[]string{"toggleOff", "web", "dev"}, kubeCmd{namespaceArg: "dev"}, true},
+		{[]string{"toggleOff", "app=web", "dev"}, kubeCmd{allNamespaces: true}, true},
+		{[]string{"setScale", "app=web", "3", "dev"}, kubeCmd{namespaceLabelArg: "team=payments"}, true},
+		{[]string{"toggleOff", "app=web"}, kubeCmd{namespaceArg: "dev", allNamespaces: true}, true},
+		{[]string{"toggleOff", "web", "api"}, kubeCmd{namespaceArg: "dev"}, false},
+		{[]string{"setScale", "app=web", "3"}, kubeCmd{allNamespaces: true}, false},
+		{[]string{"toggleOff", "web", "dev"}, kubeCmd{}, false},
+	}
+	for _, c := range cases {
+		if err := checkNamespaceFlags(c.positional, c.args); (err != nil) != c.exErr {
+			t.Errorf("Returned incorrect error for %v with %+v, got: %v, want error: %v", c.positional, c.args, err, c.exErr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+/* defaultConfirmThreshold is the number of objects a mutating command can change before it asks for confirmation */
+const defaultConfirmThreshold = 10
+
+/* defaultProtectedNamespaces is the comma-separated list of namespace patterns where every change asks for confirmation */
+const defaultProtectedNamespaces = "*prod*"
+
+/* parseNamespacePatterns splits a comma-separated --protected-namespaces value into glob patterns like *prod*, checking that each
+   one is a valid path.Match pattern */
+func parseNamespacePatterns(patternArg string) ([]string, error) {
+	patterns := []string{}
+	for _, pattern := range strings.Split(patternArg, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("error: invalid protected namespace pattern %q: %v", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+/* protectedPattern returns the first pattern the namespace matches, or an empty string if it matches none */
+func protectedPattern(namespace string, patterns []string) string {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return pattern
+		}
+	}
+	return ""
+}
+
+/* countTargets returns the number of objects in a set of targets */
+func countTargets(targets []namespaceTargets) int {
+	count := 0
+	for _, target := range targets {
+		count += len(target.names)
+	}
+	return count
+}
+
+/* skipsProtected returns true if the command leaves protected workloads alone, which every command that stops or scales them does
+   unless it is forced */
+func skipsProtected(args kubeCmd) bool {
+	switch args.cmd {
+	case "toggleOff", "setScale", "reset", "suspend", "restore":
+		return !args.force
+	}
+	return false
+}
+
+/* confirmTargets guards a mutating command against a broader match than intended, aborting over --max-targets and otherwise asking
+   on the Toggler's input whether to go on when the targets are many or in a protected namespace */
+func (t *Toggler) confirmTargets(args kubeCmd, targets []namespaceTargets) error {
+	count := countTargets(targets)
+	if args.maxTargets > 0 && count > args.maxTargets {
+		return fmt.Errorf("error: %s matches %d objects, more than --max-targets %d", args.cmd, count, args.maxTargets)
+	}
+	if args.yes || args.dryRun != "" {
+		return nil
+	}
+
+	reasons := []string{}
+	if count > args.confirmThreshold {
+		reasons = append(reasons, fmt.Sprintf("%d objects is more than --confirm-threshold %d", count, args.confirmThreshold))
+	}
+	for i, target := range targets {
+		if i > 0 && targets[i-1].namespace == target.namespace {
+			continue
+		}
+		if pattern := protectedPattern(target.namespace, args.protectedNamespaces); pattern != "" {
+			reasons = append(reasons, fmt.Sprintf("namespace %s is protected (%s)", target.namespace, pattern))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	//Protected workloads are listed too, but aren't counted as changed since the command will skip them
+	lines := []string{}
+	for _, target := range targets {
+		for _, n := range target.names {
+			line := fmt.Sprintf("  %s: %s", target.namespace, displayName(target.kind, n))
+			if skipsProtected(args) {
+				if w, err := t.getWorkload(target.kind, n, target.namespace); err == nil && isProtected(w) {
+					line += " (protected, will be skipped)"
+					count--
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	fmt.Fprintf(t.out, "%s will change %d object(s):\n", args.cmd, count)
+	for _, line := range lines {
+		fmt.Fprintf(t.out, "%s\n", line)
+	}
+	for _, reason := range reasons {
+		fmt.Fprintf(t.out, "%s\n", reason)
+	}
+	fmt.Fprint(t.out, "Continue? [y/N]: ")
+
+	//Anything but y or yes, including no input at all, aborts
+	answer, _ := bufio.NewReader(t.in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.New("error: aborted, nothing was changed (pass --yes to skip confirmation)")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+/* guardArgs returns the kubeCmd fields confirmTargets reads, with the default threshold and protected namespace pattern */
+func guardArgs() kubeCmd {
+	return kubeCmd{cmd: "toggleOff", confirmThreshold: defaultConfirmThreshold, protectedNamespaces: []string{"*prod*"}}
+}
+
+/*
+	Unit test confirmTargets
+*/
+
+//Tests a small match outside protected namespaces. Should go ahead without asking
+func TestConfirmTargets_NoPrompt(t *testing.T) {
+	toggler, _, out := newBufferedToggler()
+	toggler.in = strings.NewReader("")
+	targets := []namespaceTargets{{namespace: "dev-1", kind: kindDeployment, names: []string{"payments-api"}}}
+	if err := toggler.confirmTargets(guardArgs(), targets); err != nil || out.Len() != 0 {
+		t.Errorf("Expected no prompt, got: %q, error: %v", out.String(), err)
+	}
+}
+
+//Tests a protected namespace answered with y. Should print the matched objects and the reason and go ahead
+func TestConfirmTargets_ProtectedConfirmed(t *testing.T) {
+	toggler, _, out := newBufferedToggler()
+	toggler.in = strings.NewReader("y\n")
+	targets := []namespaceTargets{
+		{namespace: "payments-prod", kind: kindDeployment, names: []string{"payments-api"}},
+		{namespace: "payments-prod", kind: kindStatefulSet, names: []string{"payments-db"}},
+	}
+	err := toggler.confirmTargets(guardArgs(), targets)
+	exOut := "toggleOff will change 2 object(s):\n" +
+		"  payments-prod: payments-api\n" +
+		"  payments-prod: statefulset/payments-db\n" +
+		"namespace payments-prod is protected (*prod*)\n" +
+		"Continue? [y/N]: "
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect prompt, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests a protected namespace holding a protected workload. Should list the workload as skipped without counting it as changed
+func TestConfirmTargets_ProtectedWorkload(t *testing.T) {
+	ingress := newProtectedDeployment("ingress-controller", nil, 2)
+	ingress.Namespace = "payments-prod"
+	toggler, _, out := newBufferedToggler(ingress, newDeployment("payments-api", "payments-prod", nil, 2))
+	toggler.in = strings.NewReader("y\n")
+	targets := []namespaceTargets{{namespace: "payments-prod", kind: kindDeployment, names: []string{"ingress-controller", "payments-api"}}}
+	err := toggler.confirmTargets(guardArgs(), targets)
+	exOut := "toggleOff will change 1 object(s):\n" +
+		"  payments-prod: ingress-controller (protected, will be skipped)\n" +
+		"  payments-prod: payments-api\n" +
+		"namespace payments-prod is protected (*prod*)\n" +
+		"Continue? [y/N]: "
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect prompt, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests more objects than the threshold with no answer, as when stdin is not a terminal. Should abort
+func TestConfirmTargets_ThresholdNoAnswer(t *testing.T) {
+	toggler, _, out := newBufferedToggler()
+	toggler.in = strings.NewReader("")
+	args := guardArgs()
+	args.confirmThreshold = 1
+	targets := []namespaceTargets{{namespace: "dev-1", kind: kindDeployment, names: []string{"payments-api", "payments-worker"}}}
+	err := toggler.confirmTargets(args, targets)
+	if err == nil || !strings.Contains(out.String(), "2 objects is more than --confirm-threshold 1") {
+		t.Errorf("Expected abort, got: %q, error: %v", out.String(), err)
+	}
+}
+
+//Tests --yes and --dry-run on a protected namespace. Should go ahead without asking
+func TestConfirmTargets_YesAndDryRun(t *testing.T) {
+	targets := []namespaceTargets{{namespace: "prod", kind: kindDeployment, names: []string{"payments-api"}}}
+	toggler, _, out := newBufferedToggler()
+	toggler.in = strings.NewReader("")
+	args := guardArgs()
+	args.yes = true
+	if err := toggler.confirmTargets(args, targets); err != nil || out.Len() != 0 {
+		t.Errorf("Expected --yes to skip the prompt, got: %q, error: %v", out.String(), err)
+	}
+	args = guardArgs()
+	args.dryRun = dryRunClient
+	if err := toggler.confirmTargets(args, targets); err != nil || out.Len() != 0 {
+		t.Errorf("Expected --dry-run to skip the prompt, got: %q, error: %v", out.String(), err)
+	}
+}
+
+//Tests --max-targets together with --yes. Should abort anyway
+func TestConfirmTargets_MaxTargets(t *testing.T) {
+	toggler, _, _ := newBufferedToggler()
+	toggler.in = strings.NewReader("y\n")
+	args := guardArgs()
+	args.yes = true
+	args.maxTargets = 1
+	targets := []namespaceTargets{{namespace: "dev-1", kind: kindDeployment, names: []string{"payments-api", "payments-worker"}}}
+	if err := toggler.confirmTargets(args, targets); err == nil {
+		t.Errorf("Expected error for more than --max-targets objects")
+	}
+}
+
+/*
+	Unit test parseNamespacePatterns
+*/
+
+//Tests parsing namespace patterns. Should split on commas and reject invalid patterns
+func TestParseNamespacePatterns(t *testing.T) {
+	out, err := parseNamespacePatterns("*prod*, kube-system")
+	if err != nil || len(out) != 2 || protectedPattern("kube-system", out) != "kube-system" || protectedPattern("dev-1", out) != "" {
+		t.Errorf("Returned incorrect patterns, got: %v, error: %v", out, err)
+	}
+	if _, err := parseNamespacePatterns("[prod"); err == nil {
+		t.Errorf("Expected error for invalid pattern")
+	}
+}
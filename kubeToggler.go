@@ -1,16 +1,15 @@
 /*
 	Ryan Robinson, 2021
 
-	kubeToggler is a lightweight command line tool built using the client-go API that can retreive kubernetes deployments by their labels or names
-	and then set/get some of their attributes. Currently, kubeToggler can set/get deployment scales from labels, get deployment names from
-	labels, and get the number of deployments in a given namespace with specified labels.
-
-	TODO: Add unit tests and integration tests
+	kubeToggler is a lightweight command line tool built using the client-go API that can retrieve kubernetes workloads by their labels or names
+	and then set/get some of their attributes. Every operation runs through a Toggler, which wraps any kubernetes.Interface so that
+	kubeToggler can also be used as a library and is tested against the fake clientset.
 */
 
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
@@ -18,36 +17,20 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
-func printMap(m map[string]string) {
-	for k, v := range m {
-		fmt.Printf("%s: %s\n", k, v)
-	}
-}
-func printArr(arr []string) {
-	for _, v := range arr {
-		fmt.Print(v + " ")
-	}
-}
-func isSubset(subset map[string]string, set map[string]string) bool {
-	for key, val := range subset {
-		if set[key] != val {
-			return false
-		}
-	}
-	return true
-}
 func getTimeElapsed(timestamp string) (time.Duration, error) {
 	pastTime, err := time.Parse("2006-01-02|15:04 UTC", timestamp)
 	if err != nil {
@@ -58,7 +41,14 @@ func getTimeElapsed(timestamp string) (time.Duration, error) {
 	return d, nil
 }
 
-/* checkMap returns true if an array is in this format {key=value, key=value ...} and false otherwise */
+/* isLabelArg returns true if a target argument is a label selector expression (key=value, key!=value, !key, key in (a,b) ...)
+   rather than a deployment name. Deployment names can't contain any of these characters, but a bare key can't be told apart from a
+   name, so "key exists" selectors have to be passed with --selector */
+func isLabelArg(str string) bool {
+	return strings.ContainsAny(str, "=! ()")
+}
+
+/* checkMap returns true if an array is made of label selector expressions {key=value, key!=value, ...} and false if it is made of names */
 func checkMap(strArr []string) (bool, error) {
 	dLCounter := 0
 	if len(strArr) == 0 {
@@ -69,7 +59,7 @@ func checkMap(strArr []string) (bool, error) {
 		//Makes sure there isnt an empty string on any side of the equals sign
 		if strings.Contains(str, "=") && (str[0] == '=' || str[len(str)-1] == '=') {
 			return false, errors.New("error: invalid label argument(s)")
-		} else if strings.Contains(str, "=") {
+		} else if isLabelArg(str) {
 			dLCounter++
 		} else {
 			dLCounter--
@@ -85,67 +75,164 @@ func checkMap(strArr []string) (bool, error) {
 	}
 }
 
-/* convStringsToMap takes a string array of format {key=value, key=value ...} and returns a map of format {key: value, key: value, ...} */
-func convStringsToMap(strArr []string) (map[string]string, error) {
-	strMap := make(map[string]string)
+/* convStringsToSelector takes a string array of label selector expressions {key=value, env!=prod, tier in (web,api), !canary, team ...}
+   and parses them with labels.Parse into a single labels.Selector that requires all of them. An empty array selects everything */
+func convStringsToSelector(strArr []string) (labels.Selector, error) {
 	for _, str := range strArr {
-		if str[0] == '=' || str[len(str)-1] == '=' || !strings.Contains(str, "=") {
+		if str == "" || str[0] == '=' || str[len(str)-1] == '=' {
 			return nil, errors.New("error: invalid label argument(s)")
 		}
-		dL := strings.Index(str, "=")
-		strMap[str[:dL]] = str[dL+1:]
 	}
-	return strMap, nil
+	selector, err := labels.Parse(strings.Join(strArr, ","))
+	if err != nil {
+		return nil, fmt.Errorf("error: invalid label argument(s): %v", err)
+	}
+	return selector, nil
 }
 
 /* kubeCmd is a struct that holds all required arguments to execute a kubeToggler command. */
 type kubeCmd struct {
 	cmd       string
-	labels    map[string]string
+	selector  labels.Selector
 	names     []string
 	scale     int32
 	namespace string
+	client    clientOptions
+	labelArg  string
+	kindArg   string
+	kinds     []string
+
+	namespaceArg      string
+	allNamespaces     bool
+	namespaceLabelArg string
+	namespaceSelector labels.Selector
+
+	resetMode string
+	wait      bool
+	timeout   time.Duration
+	cronJobs  bool
+	output    string
+	sortBy    string
+	dryRunArg string
+	dryRun    string
+
+	//Guards against changing more than intended, see confirmTargets
+	yes                    bool
+	maxTargets             int
+	confirmThreshold       int
+	protectedNamespacesArg string
+	protectedNamespaces    []string
+	force                  bool
+
+	concurrency int
+	verbose     bool
+	follow      bool
+	merge       bool
+
+	//The getPodLogs flags, --since-time is parsed into logs.sinceTime and the filter flags into logs.filter
+	logs          logOptions
+	sinceTimeArg  string
+	grepArg       string
+	jsonFilterArg string
+	beforeContext int
+	afterContext  int
+	contextLines  int
+
+	//The snapshot file restore reads
+	file string
 }
 
-/* initClientSet scans for a kubernetes config file in the local '.kube' diretory. If one is found, it uses it to create and return a
-   kubernetes.Clientset struct (https://pkg.go.dev/k8s.io/client-go/kubernetes#Clientset) */
-func initClientSet() (kubernetes.Clientset, error) {
+/* multiNamespace returns true if the command's NAMESPACE argument or flags can stand for more than one namespace, in which case its
+   output is grouped by namespace */
+func (args kubeCmd) multiNamespace() bool {
+	return args.allNamespaces || args.namespaceLabelArg != "" || strings.Contains(args.namespace, ",")
+}
 
-	//Scaning for kubernetes .config in local .kube directory
-	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+/* Toggler holds the kubernetes client every kubeToggler operation runs against. Build one with NewToggler */
+type Toggler struct {
+	clientset kubernetes.Interface
+	out       io.Writer
+	in        io.Reader
+
+	//Set from --dry-run, --force, --concurrency and --verbose. report is only set by the mutating commands
+	dryRun      string
+	force       bool
+	concurrency int
+	report      *report
+	verbose     bool
+}
 
-	//Creates a clientcmd.ClientConfig struct which is used to create a *rest.Config struct
-	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
-	config, err := kubeconfig.ClientConfig()
+/* NewToggler returns a Toggler that runs its operations against the given kubernetes.Interface. Any implementation works,
+   including the fake clientset from k8s.io/client-go/kubernetes/fake. Progress messages are written to os.Stdout, confirmations
+   are read from os.Stdin and up to defaultConcurrency objects are worked on at the same time */
+func NewToggler(clientset kubernetes.Interface) *Toggler {
+	return &Toggler{clientset: clientset, out: os.Stdout, in: os.Stdin, concurrency: defaultConcurrency}
+}
 
+/* NewTogglerFromConfig builds a kubernetes clientset from the given clientOptions with initClientSet and wraps it in a Toggler */
+func NewTogglerFromConfig(opts clientOptions) (*Toggler, error) {
+	clientset, err := initClientSet(opts)
 	if err != nil {
-		return kubernetes.Clientset{}, err
+		return nil, err
 	}
+	return NewToggler(clientset), nil
+}
+
+/* listPageSize is the number of objects requested per page when listing a namespace */
+var listPageSize int64 = 500
 
-	//Attempts to create a kubernetes.Clientset struct from 'config,' panics if failure
-	return *kubernetes.NewForConfigOrDie(config), nil
+/* listPages calls list with the ListOptions of every page of the objects matching the given selector, listPageSize objects at a time,
+   until list returns an empty continue token. The selector is sent to the API server so only matching objects are returned */
+func listPages(selector labels.Selector, list func(options metav1.ListOptions) (string, error)) error {
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	options := metav1.ListOptions{LabelSelector: selector.String(), Limit: listPageSize}
+	for {
+		continueToken, err := list(options)
+		if err != nil {
+			return err
+		}
+
+		//An empty continue token means this was the last page
+		if continueToken == "" {
+			return nil
+		}
+		options.Continue = continueToken
+	}
 }
 
-/* getDeploymentNameWithLabels searches the given namespace for deployments that contain the labels specified in the labels map
-   and returns a slice of all their names */
-func GetDeploymentNamesWithLabels(labels map[string]string, namespace string) ([]string, error) {
-	clientset, err := initClientSet()
+/* listDeployments returns the deployments in the given namespace whose labels match the given selector. A nil selector lists every
+   deployment */
+func (t *Toggler) listDeployments(selector labels.Selector, namespace string) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+	err := listPages(selector, func(options metav1.ListOptions) (string, error) {
+		page, err := t.clientset.AppsV1().Deployments(namespace).List(context.Background(), options)
+		if err != nil {
+			return "", err
+		}
+		deployments = append(deployments, page.Items...)
+		return page.Continue, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return deployments, nil
+}
+
+/* getDeploymentNameWithLabels searches the given namespace for deployments whose labels match the given label selector
+   and returns a slice of all their names */
+func (t *Toggler) GetDeploymentNamesWithLabels(selector labels.Selector, namespace string) ([]string, error) {
 
-	//Gets a list of deployments in the given namespace
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.Background(), metav1.ListOptions{})
+	//Gets a list of the matching deployments in the given namespace
+	deployments, err := t.listDeployments(selector, namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	//Loops through all deployments. If the given labels match a deployment's labels, return the deployment name
 	names := []string{}
-	for _, deps := range deployments.Items {
-		if isSubset(labels, deps.GetLabels()) {
-			names = append(names, deps.GetName())
-		}
+	for _, deps := range deployments {
+		names = append(names, deps.GetName())
 	}
 	if len(names) == 0 {
 		return nil, errors.New("error: deployment does not exist")
@@ -153,126 +240,170 @@ func GetDeploymentNamesWithLabels(labels map[string]string, namespace string) ([
 	return names, nil
 }
 
-/* getNames takes a map of labels and an array of names. If the name argument is nil, getNames uses the labels to fetch each deployment's
-   name and returns an array of names. Otherwise, get names just returns the unchanged names argument */
-func getNames(labels map[string]string, names []string, namespace string) ([]string, error) {
-	if names == nil && labels == nil {
+/* getNames takes a kind, a label selector and an array of names. If the name argument is nil, getNames uses the selector to fetch the
+   name of each workload of that kind and returns an array of names. Otherwise, get names just returns the unchanged names argument */
+func (t *Toggler) getNames(kind string, selector labels.Selector, names []string, namespace string) ([]string, error) {
+	if names == nil && selector == nil {
 		return nil, errors.New("error: there must be at least one targeting field (either names or labels)")
 	}
 	if names != nil {
 		return names, nil
 	}
-	deploymentNames, err := GetDeploymentNamesWithLabels(labels, namespace)
+	workloads, err := t.listWorkloads(kind, selector, namespace)
 	if err != nil {
 		return nil, err
 	}
-	return deploymentNames, nil
+	workloadNames := []string{}
+	for _, w := range workloads {
+		workloadNames = append(workloadNames, w.name)
+	}
+	if len(workloadNames) == 0 {
+		return nil, fmt.Errorf("error: %s does not exist", kindsDescription([]string{kind}))
+	}
+	return workloadNames, nil
 }
 
 /* getDeploymentScaleWithLabels finds the deployments in the given namespace with the given labels or names in the
-   names array and then returns a map mapping deployment names to their current scales */
-func GetDeploymentScales(labels map[string]string, names []string, namespace string) (map[string]string, error) {
-	clientset, err := initClientSet()
-	if err != nil {
-		return nil, err
-	}
-	deploymentNames, err := getNames(labels, names, namespace)
+   names array and then returns their current scales */
+func (t *Toggler) GetDeploymentScales(selector labels.Selector, names []string, namespace string) (scaleResults, error) {
+	return t.GetScales(kindDeployment, selector, names, namespace)
+}
+
+/* GetScales finds the workloads of the given kind in the given namespace with the given labels or names in the names array and then
+   returns a scaleResult for each one, holding its desired, current and ready replicas and its labels */
+func (t *Toggler) GetScales(kind string, selector labels.Selector, names []string, namespace string) (scaleResults, error) {
+	workloadNames, err := t.getNames(kind, selector, names, namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	//Maps deployment names to their scales
-	scales := make(map[string]string)
-	for _, n := range deploymentNames {
-
-		//Gets the deployment with the given name in the given namespace
-		deploymentScale, err := clientset.AppsV1().Deployments(namespace).GetScale(context.Background(), n, metav1.GetOptions{})
-
+	found := make([]*scaleResult, len(workloadNames))
+	err = t.forEach(len(workloadNames), func(worker *Toggler, i int) error {
+		w, err := worker.getWorkload(kind, workloadNames[i], namespace)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		scales[n] = strconv.Itoa(int(deploymentScale.Spec.Replicas))
-	}
+		found[i] = &scaleResult{
+			Namespace:       namespace,
+			Kind:            kind,
+			Name:            workloadNames[i],
+			Replicas:        w.specReplicas,
+			CurrentReplicas: w.replicas,
+			ReadyReplicas:   w.readyReplicas,
+			Labels:          w.labels,
+		}
+		return nil
+	})
 
-	return scales, nil
+	results := scaleResults{}
+	for _, r := range found {
+		if r != nil {
+			results = append(results, *r)
+		}
+	}
+	return results, err
 }
 
 /* setDeploymentScale finds the deployments in the given namespace with the given labels or names and then scales them to 'scale.'
    Returns an array of autoscalingv1.Scale structs (https://pkg.go.dev/k8s.io/api/autoscaling/v1#Scale) */
-func SetDeploymentScales(labels map[string]string, names []string, scale int32, namespace string) ([]*v1.Scale, error) {
-	deploymentNames, err := getNames(labels, names, namespace)
-	if err != nil {
-		return nil, err
-	}
-	clientset, err := initClientSet()
+func (t *Toggler) SetDeploymentScales(selector labels.Selector, names []string, scale int32, namespace string) ([]*v1.Scale, error) {
+	return t.SetScales(kindDeployment, selector, names, scale, namespace)
+}
+
+/* SetScales finds the workloads of the given kind in the given namespace with the given labels or names and then scales them to
+   'scale,' skipping protected workloads unless the Toggler is forced. Returns the autoscalingv1.Scale of every scaled workload */
+func (t *Toggler) SetScales(kind string, selector labels.Selector, names []string, scale int32, namespace string) ([]*v1.Scale, error) {
+	workloadNames, err := t.getNames(kind, selector, names, namespace)
 	if err != nil {
 		return nil, err
 	}
+	workloadNames, protectErr := t.unprotected(kind, workloadNames, namespace)
 
-	v1scales := []*v1.Scale{}
-	for _, n := range deploymentNames {
+	v1scales := make([]*v1.Scale, len(workloadNames))
+	err = t.forEach(len(workloadNames), func(worker *Toggler, i int) error {
+		v1scale, err := worker.setScale(kind, workloadNames[i], scale, namespace)
+		v1scales[i] = v1scale
+		return worker.recordResult(kind, workloadNames[i], namespace, err)
+	})
+	return compactScales(v1scales), joinErrors(protectErr, err)
+}
 
-		//Gets the deployment's autoscalingv1.Scale struct
-		deploymentScale, err := clientset.AppsV1().Deployments(namespace).GetScale(context.Background(), n, metav1.GetOptions{})
-		if err != nil {
-			return nil, err
+/* compactScales drops the nil entries a parallel operation leaves for the workloads it failed on */
+func compactScales(v1scales []*v1.Scale) []*v1.Scale {
+	compacted := []*v1.Scale{}
+	for _, v1scale := range v1scales {
+		if v1scale != nil {
+			compacted = append(compacted, v1scale)
 		}
+	}
+	return compacted
+}
 
-		//Updates the autoscalingv1.Scale struct to the new value, updates the deployment scale
-		deploymentScalePoiner := *deploymentScale
-		deploymentScalePoiner.Spec.Replicas = scale
-		v1scale, err := clientset.AppsV1().Deployments(namespace).UpdateScale(context.Background(), n, &deploymentScalePoiner, metav1.UpdateOptions{})
-		if err != nil {
-			return nil, err
-		}
-		v1scales = append(v1scales, v1scale)
+/* setScale scales the workload of the given kind with the given name in the given namespace to 'scale' through its scale subresource
+   and prints the workload's "before -> after" replica counts. Conflicts and transient errors are retried with the Toggler's retry. In
+   dryRunClient it returns the scale it would have set without sending it */
+func (t *Toggler) setScale(kind string, name string, scale int32, namespace string) (*v1.Scale, error) {
+	if kind == kindDaemonSet {
+		return nil, fmt.Errorf("error: daemonset %s has no replicas to scale, use toggleOff and toggleOn instead", name)
 	}
 
-	return v1scales, nil
-}
+	workloadScale, updated := (*v1.Scale)(nil), (*v1.Scale)(nil)
+	err := t.retry(kind, name, func() error {
 
-/* getNumDeploymentsWithLabels returns the count of the number of deployments that contain the given labels in the given namespace */
-func GetNumDeploymentsWithLabels(labels map[string]string, namespace string) (int, error) {
-	clientset, err := initClientSet()
+		//Gets the workload's autoscalingv1.Scale struct on every attempt, so a conflict is retried against its latest version
+		current, err := t.scaleClient(kind, namespace).GetScale(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		workloadScale = current
+
+		//Updates the autoscalingv1.Scale struct to the new value, updates the workload scale
+		workloadScalePoiner := *current
+		workloadScalePoiner.Spec.Replicas = scale
+		if t.dryRun == dryRunClient {
+			updated = &workloadScalePoiner
+			return nil
+		}
+		updated, err = t.scaleClient(kind, namespace).UpdateScale(context.Background(), name, &workloadScalePoiner, metav1.UpdateOptions{DryRun: t.dryRunOptions()})
+		return err
+	})
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
+	fmt.Fprintf(t.out, "%s: %d -> %d%s\n", displayName(kind, name), workloadScale.Spec.Replicas, scale, t.dryRunSuffix())
+	return updated, nil
+}
 
-	//Gets a list of deployments in the given namespace
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.Background(), metav1.ListOptions{})
+/* getNumDeploymentsWithLabels returns the count of the number of deployments in the given namespace whose labels match the given selector */
+func (t *Toggler) GetNumDeploymentsWithLabels(selector labels.Selector, namespace string) (int, error) {
+
+	//Gets a list of the matching deployments in the given namespace
+	deployments, err := t.listDeployments(selector, namespace)
 	if err != nil {
 		return -1, err
 	}
-
-	//If a deployment contains all labels given, increment the counter
-	counter := 0
-	for _, deps := range deployments.Items {
-		if isSubset(labels, deps.GetLabels()) {
-			counter++
-		}
-	}
-	return counter, nil
+	return len(deployments), nil
 }
 
 /* GetPods takes the name and namespace of a deployment and returns an array of pods currently running in that deployment */
-func getPods(deploymentName string, namespace string) ([]corev1.Pod, error) {
-	clientset, err := initClientSet()
-	if err != nil {
-		return nil, err
-	}
-	deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), deploymentName, metav1.GetOptions{})
+func (t *Toggler) getPods(deploymentName string, namespace string) ([]corev1.Pod, error) {
+	deployment, err := t.clientset.AppsV1().Deployments(namespace).Get(context.Background(), deploymentName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
+	return t.getPodsForSelector(deployment.Spec.Selector, namespace)
+}
 
-	labelMap, err := metav1.LabelSelectorAsMap(deployment.Spec.Selector)
+/* getPodsForSelector returns the pods in the given namespace that match a workload's pod selector */
+func (t *Toggler) getPodsForSelector(labelSelector *metav1.LabelSelector, namespace string) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
 	if err != nil {
 		return nil, err
 	}
 	options := metav1.ListOptions{
-		LabelSelector: labels.SelectorFromSet(labelMap).String(),
+		LabelSelector: selector.String(),
 	}
-	podList, err := clientset.CoreV1().Pods(namespace).List(context.Background(), options)
+	podList, err := t.clientset.CoreV1().Pods(namespace).List(context.Background(), options)
 	if err != nil {
 		return nil, err
 	}
@@ -282,8 +413,8 @@ func getPods(deploymentName string, namespace string) ([]corev1.Pod, error) {
 
 /* GetPodCreationTimestamps takes the name and namespace of a deployment and returns a map mapping the deployment's
    pods to their time of creation. Time is formatted like 2006-01-02|15:04:05 UTC*/
-func GetPodCreationTimestamps(deploymentName string, namespace string) (map[string]string, error) {
-	pods, err := getPods(deploymentName, namespace)
+func (t *Toggler) GetPodCreationTimestamps(deploymentName string, namespace string) (map[string]string, error) {
+	pods, err := t.getPods(deploymentName, namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -294,123 +425,367 @@ func GetPodCreationTimestamps(deploymentName string, namespace string) (map[stri
 	return podTimeStamps, nil
 }
 
-/* GetPodCreationTimestamps takes the name and namespace of a deployment and returns a map mapping the deployment's
-   pods to their time of creation. Time is formatted like 2006-01-02|15:04:05 UTC*/
-func GetPodLifetimes(deploymentName string, namespace string) (map[string]string, error) {
-	podTimestamps, err := GetPodCreationTimestamps(deploymentName, namespace)
+/* GetPodLifetimes takes the name and namespace of a deployment and returns a podLifetimeResult for each of the deployment's pods,
+   holding its creation time, how long it has been running, how often its containers restarted and its labels */
+func (t *Toggler) GetPodLifetimes(deploymentName string, namespace string) (podLifetimeResults, error) {
+	pods, err := t.getPods(deploymentName, namespace)
 	if err != nil {
 		return nil, err
 	}
-	podLifetimes := make(map[string]string)
-	for name, timestamp := range podTimestamps {
-		duration, err := getTimeElapsed(timestamp)
+	results := podLifetimeResults{}
+	for _, pod := range pods {
+		created := pod.CreationTimestamp.UTC()
+		lifetime, err := getTimeElapsed(created.Format("2006-01-02|15:04 UTC"))
 		if err != nil {
 			return nil, err
 		}
-		podLifetimes[name] = duration.String()
-	}
-	return podLifetimes, nil
+		restarts := int32(0)
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += status.RestartCount
+		}
+		results = append(results, podLifetimeResult{
+			Namespace: namespace,
+			Pod:       pod.Name,
+			Created:   created,
+			Lifetime:  lifetime.String(),
+			Restarts:  restarts,
+			Labels:    pod.Labels,
+		})
+	}
+	return results, nil
 }
 
-/* GetPodCreationTimestamps takes the name and namespace of a deployment and returns a map mapping the deployment's
-   pods to their logs */
-func GetPodLogs(deploymentName string, namespace string) (map[string]string, error) {
-	clientset, err := initClientSet()
-	if err != nil {
-		return nil, err
+/* GetPodLogs takes the name and namespace of a deployment and returns a map mapping every container of the deployment's pods, as
+   "pod/container", to its whole log */
+func (t *Toggler) GetPodLogs(deploymentName string, namespace string) (map[string]string, error) {
+	results, err := t.podLogResults(deploymentName, namespace, allLogs)
+	podLogs := make(map[string]string)
+	for _, r := range results {
+		podLogs[r.source()] = r.Log
 	}
-	pods, err := getPods(deploymentName, namespace)
-
-	podLogOpts := corev1.PodLogOptions{}
+	return podLogs, err
+}
 
+/* getPodLog returns the part of the log chosen by opts of the given container of the pod with the given name in the given namespace.
+   If opts has a filter the log is filtered line by line as it is read, so only the lines that pass are held in memory */
+func (t *Toggler) getPodLog(podName string, container string, namespace string, opts logOptions) (string, error) {
+	logs := t.clientset.CoreV1().Pods(namespace).GetLogs(podName, opts.podLogOptions(container, false))
+	req, err := logs.Stream(context.Background())
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	podLogs := make(map[string]string)
-	for _, pod := range pods {
-		logs := clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &podLogOpts)
-		req, err := logs.Stream(context.Background())
+	defer req.Close()
+
+	buf := new(bytes.Buffer)
+	if opts.filter == nil {
+		_, err = io.Copy(buf, req)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		defer req.Close()
+		return buf.String(), nil
+	}
 
-		buf := new(bytes.Buffer)
-		_, err = io.Copy(buf, req)
+	filter := opts.filter.newContext()
+	reader := bufio.NewReader(req)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			for _, l := range filter.next(strings.TrimSuffix(line, "\n")) {
+				buf.WriteString(l + "\n")
+			}
+		}
+		if err == io.EOF {
+			return buf.String(), nil
+		}
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		logStr := buf.String()
+	}
+}
 
-		podLogs[pod.Name] = logStr
+/* nameResults returns the getName result, holding the name and labels, of every workload of the given targets */
+func (t *Toggler) nameResults(target namespaceTargets) (nameResults, error) {
+	results := nameResults{}
+	for _, n := range target.names {
+
+		//Workloads found with a selector come with their labels, only those targeted by name are looked up
+		workloadLabels, listed := target.labels[n]
+		if !listed {
+			w, err := t.getWorkload(target.kind, n, target.namespace)
+			if err != nil {
+				return nil, err
+			}
+			workloadLabels = w.labels
+		}
+		results = append(results, nameResult{Namespace: target.namespace, Kind: target.kind, Name: n, Labels: workloadLabels})
 	}
-	return podLogs, nil
+	return results, nil
 }
 
-/* doCommand takes a kubeCmd struct and executes the command it specifies */
-func doCommand(args kubeCmd) {
+/* podLogResults returns the getPodLogs result of every container chosen by opts of every pod of the given deployment in the given
+   namespace */
+func (t *Toggler) podLogResults(deploymentName string, namespace string, opts logOptions) (podLogResults, error) {
+	pods, err := t.getPods(deploymentName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]podLogResults, len(pods))
+	err = t.forEach(len(pods), func(worker *Toggler, i int) error {
+		errs := []error{}
+		for _, c := range opts.containers(pods[i]) {
+			podLog, err := worker.getPodLog(pods[i].Name, c, namespace, opts)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			found[i] = append(found[i], podLogResult{Namespace: namespace, Pod: pods[i].Name, Container: c, Log: podLog})
+		}
+		return joinErrors(errs...)
+	})
+
+	results := podLogResults{}
+	for _, r := range found {
+		results = append(results, r...)
+	}
+	return results, err
+}
+
+/* doCommand takes a kubeCmd struct and executes the command it specifies against the given Toggler */
+func doCommand(t *Toggler, args kubeCmd) {
 	switch args.cmd {
 	case "empty":
 		fmt.Println("A lightweight command line tool that can target Kubernetes deployments by their labels and retrieve/modify their attributes. Reference README for arguments.")
 	case "getNumWithLabels":
-		num, err := GetNumDeploymentsWithLabels(args.labels, args.namespace)
+		namespaces, err := t.ResolveNamespaces(args.namespace, args.namespaceSelector)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		fmt.Println(num)
+		results := countResults{}
+		for _, ns := range namespaces {
+			num, err := t.GetNumDeploymentsWithLabels(args.selector, ns)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			results = append(results, countResult{Namespace: ns, Count: num})
+		}
+		printCommandResults(t, args, results)
 	case "getName":
-		names, err := GetDeploymentNamesWithLabels(args.labels, args.namespace)
+		targets, err := t.commandTargets(args)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		printArr(names)
+		results := nameResults{}
+		for _, target := range targets {
+			targetResults, err := t.nameResults(target)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			results = append(results, targetResults...)
+		}
+		printCommandResults(t, args, results)
 	case "getScale":
-		scales, err := GetDeploymentScales(args.labels, args.names, args.namespace)
+		targets, err := t.commandTargets(args)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		printMap(scales)
-	case "setScale":
-		_, err := SetDeploymentScales(args.labels, args.names, args.scale, args.namespace)
+		results := scaleResults{}
+		for _, target := range targets {
+			targetResults, err := t.GetScales(target.kind, nil, target.names, target.namespace)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			results = append(results, targetResults...)
+		}
+		printCommandResults(t, args, results)
+	case "setScale", "toggleOn", "toggleOff":
+		targets, err := t.commandTargets(args)
 		if err != nil {
 			log.Fatalln(err)
 		}
-	case "toggleOn":
-		_, err := SetDeploymentScales(args.labels, args.names, 1, args.namespace)
-		if err != nil {
+		if err := t.confirmTargets(args, targets); err != nil {
 			log.Fatalln(err)
 		}
-	case "toggleOff":
-		_, err := SetDeploymentScales(args.labels, args.names, 0, args.namespace)
+		t.report = &report{}
+		errs := []error{}
+		for _, target := range targets {
+
+			//CronJobs picked up by --cronjobs are suspended and resumed rather than scaled
+			if target.kind == kindCronJob {
+				if args.cmd == "toggleOff" {
+					_, err = t.SuspendCronJobs(nil, target.names, target.namespace)
+				} else {
+					_, err = t.ResumeCronJobs(nil, target.names, target.namespace)
+				}
+				errs = append(errs, err)
+				continue
+			}
+
+			//DaemonSets are switched through their node selector, and once toggled on are waited for like a rollout
+			if target.kind == kindDaemonSet && args.cmd != "setScale" {
+				check := t.scaledTo(0)
+				changed := []string(nil)
+				if args.cmd == "toggleOff" {
+					changed, err = t.DisableDaemonSets(nil, target.names, target.namespace)
+				} else {
+					changed, err = t.EnableDaemonSets(nil, target.names, target.namespace)
+					check = rolledOut
+				}
+				errs = append(errs, err)
+				if args.wait && args.dryRun == "" {
+					errs = append(errs, t.waitForWorkloads(target.kind, changed, target.namespace, args.timeout, check))
+				}
+				continue
+			}
+
+			scales := []*v1.Scale(nil)
+			switch args.cmd {
+			case "setScale":
+				scales, err = t.SetScales(target.kind, nil, target.names, args.scale, target.namespace)
+			case "toggleOn":
+				scales, err = t.ToggleOn(target.kind, nil, target.names, target.namespace)
+			case "toggleOff":
+				scales, err = t.ToggleOff(target.kind, nil, target.names, target.namespace)
+			}
+			errs = append(errs, err)
+			if args.wait && args.dryRun == "" {
+				errs = append(errs, t.WaitForScales(target.kind, scales, args.timeout, target.namespace))
+			}
+		}
+		finishCommand(t, args.cmd, errs)
+	case "suspend", "resume":
+		targets, err := t.commandTargets(args)
 		if err != nil {
 			log.Fatalln(err)
 		}
+		if err := t.confirmTargets(args, targets); err != nil {
+			log.Fatalln(err)
+		}
+		t.report = &report{}
+		errs := []error{}
+		for _, target := range targets {
+			if args.cmd == "suspend" {
+				_, err = t.SuspendCronJobs(nil, target.names, target.namespace)
+			} else {
+				_, err = t.ResumeCronJobs(nil, target.names, target.namespace)
+			}
+			errs = append(errs, err)
+		}
+		finishCommand(t, args.cmd, errs)
 	case "reset":
-		_, err := SetDeploymentScales(args.labels, args.names, 0, args.namespace)
+		targets, err := t.commandTargets(args)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := t.confirmTargets(args, targets); err != nil {
+			log.Fatalln(err)
+		}
+		t.report = &report{}
+		errs := []error{}
+		for _, target := range targets {
+			errs = append(errs, t.ResetWorkloads(target.kind, nil, target.names, args.resetMode, args.timeout, target.namespace))
+		}
+		finishCommand(t, args.cmd, errs)
+	case "snapshot":
+		namespaces, err := t.ResolveNamespaces(args.namespace, args.namespaceSelector)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		_, err = SetDeploymentScales(args.labels, args.names, 1, args.namespace)
+		s, err := t.Snapshot(args.kinds, namespaces)
 		if err != nil {
 			log.Fatalln(err)
 		}
+		if err := writeSnapshot(t.out, s, args.output); err != nil {
+			log.Fatalln(err)
+		}
+	case "restore":
+		s, err := readSnapshot(args.file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		changes, drift, err := t.DiffSnapshot(s)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		t.printDiff(changes, drift)
+		if len(changes) == 0 {
+			break
+		}
+		if err := t.confirmTargets(args, restoreTargets(changes)); err != nil {
+			log.Fatalln(err)
+		}
+		t.report = &report{}
+		errs := []error{}
+		for _, c := range changes {
+			scales, err := t.SetScales(c.Kind, nil, []string{c.Name}, c.Replicas, c.Namespace)
+			errs = append(errs, err)
+			if args.wait && args.dryRun == "" {
+				errs = append(errs, t.WaitForScales(c.Kind, scales, args.timeout, c.Namespace))
+			}
+		}
+		finishCommand(t, args.cmd, errs)
 	case "getPodLifetimes":
-		lifetimes, err := GetPodLifetimes(args.names[0], args.namespace)
+		results, err := t.GetPodLifetimes(args.names[0], args.namespace)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		printMap(lifetimes)
+		printCommandResults(t, args, results)
 	case "getPodLogs":
-		logs, err := GetPodLogs(args.names[0], args.namespace)
+		if args.follow {
+
+			//Follows the logs until interrupted with ctrl-c or terminated
+			ctx, cancel := context.WithCancel(context.Background())
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			go func() {
+				<-signals
+				cancel()
+			}()
+			if err := t.FollowPodLogs(ctx, args.names[0], args.namespace, args.logs); err != nil {
+				log.Fatalln(err)
+			}
+			break
+		}
+		if args.merge {
+			if err := t.MergePodLogs(args.names[0], args.namespace, args.logs); err != nil {
+				log.Fatalln(err)
+			}
+			break
+		}
+		results, err := t.podLogResults(args.names[0], args.namespace, args.logs)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		printMap(logs)
+		printCommandResults(t, args, results)
 	case "error":
 		log.Fatalln(errors.New("args: cannot read arguments"))
 	}
 }
 
+/* printCommandResults sorts the results of a read command by namespace and name, or by the command's --sort-by field, and prints
+   them in the command's --output format */
+func printCommandResults(t *Toggler, args kubeCmd, results resultSet) {
+	if err := results.sortBy(args.sortBy); err != nil {
+		log.Fatalln(err)
+	}
+	if err := printResults(t.out, args.output, results, args.multiNamespace()); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/* finishCommand ends a mutating command. Errors that weren't already printed with the object they belong to are logged, then the
+   summary of the Toggler's report is printed, and if anything failed the process exits with exitFailure, or exitPartialFailure if
+   some objects were still changed */
+func finishCommand(t *Toggler, cmd string, errs []error) {
+	others := unreported(errs)
+	for _, err := range others {
+		log.Println(err)
+	}
+	fmt.Fprintln(t.out, t.report.summary(cmd))
+	if code := t.report.exitCode(len(others)); code != exitSuccess {
+		os.Exit(code)
+	}
+}
+
 /* getCommand takes an array of arguments, usually from os.Args, and returns the command (conventionally the second arg). If there is not
    a second argument, getCommand returns the string "empty" */
 func getCommand(osArgs []string) string {
@@ -420,19 +795,29 @@ func getCommand(osArgs []string) string {
 	return osArgs[1]
 }
 
-/* parseTargetArgs takes an array of arguments that are either in map format {key:value, key:value...} or array format {value, value} and
-   returns the data correctly formatted in a map or array. It returns nil for the data structure that the array argument is not */
-func parseTargetArgs(args []string) (labels map[string]string, names []string, err error) {
+/* parseTargetArgs takes an array of arguments that are either label selector expressions {key=value, key!=value...} or names {value, value}
+   and returns them as a labels.Selector or a names array. The value of the --selector flag, if any, is added to the selector. It returns nil
+   for whichever of the two the arguments are not */
+func parseTargetArgs(args []string, labelArg string) (selector labels.Selector, names []string, err error) {
+	if len(args) == 0 {
+		selector, err := convStringsToSelector([]string{labelArg})
+		return selector, nil, err
+	}
 	isMap, err := checkMap(args)
 	if err != nil {
 		log.Fatalln(err)
 	}
 	if isMap {
-		labels, err := convStringsToMap(args)
+		if labelArg != "" {
+			args = append(args, labelArg)
+		}
+		selector, err := convStringsToSelector(args)
 		if err != nil {
 			return nil, nil, err
 		}
-		return labels, nil, nil
+		return selector, nil, nil
+	} else if labelArg != "" {
+		return nil, nil, errors.New("error: arguments must be either labels or names, not both")
 	} else {
 		return nil, args, nil
 	}
@@ -440,37 +825,137 @@ func parseTargetArgs(args []string) (labels map[string]string, names []string, e
 
 /* parseArgs parses an array of arguments, usually from os.Args, and returns a kubeCmd struct containing all the relevant arguments */
 func parseArgs(osArgs []string) kubeCmd {
-	cmd := getCommand(osArgs)
 	args := kubeCmd{}
+	positional, err := parseFlags(osArgs[1:], &args)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	//The --namespace, --all-namespaces and --namespace-selector flags take the place of the trailing NAMESPACE argument. The
+	//latter two stand for an empty namespace, which ResolveNamespaces expands to every (matching) namespace
+	if err := checkNamespaceFlags(positional, args); err != nil {
+		log.Fatalln(err)
+	}
+	if args.namespaceArg != "" {
+		positional = append(positional, args.namespaceArg)
+	} else if args.allNamespaces || args.namespaceLabelArg != "" {
+		positional = append(positional, metav1.NamespaceAll)
+	}
+	if args.namespaceLabelArg != "" {
+		args.namespaceSelector, err = convStringsToSelector([]string{args.namespaceLabelArg})
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	osArgs = append(osArgs[:1:1], positional...)
+
+	if err := checkOutputFormat(args.output); err != nil {
+		log.Fatalln(err)
+	}
+	args.dryRun, err = checkDryRun(args.dryRunArg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	args.protectedNamespaces, err = parseNamespacePatterns(args.protectedNamespacesArg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := checkConcurrency(args.concurrency); err != nil {
+		log.Fatalln(err)
+	}
+	if err := checkResetMode(args.resetMode); err != nil {
+		log.Fatalln(err)
+	}
+
+	//getName and getNumWithLabels only look at deployments, every other command looks at the kinds chosen with --kind
+	args.kinds, err = kindsFor(args.kindArg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	switch getCommand(osArgs) {
+	case "getName", "getNumWithLabels":
+		args.kinds = []string{kindDeployment}
+	case "suspend", "resume":
+		args.kinds = []string{kindCronJob}
+	case "toggleOn", "toggleOff":
+		if args.cronJobs {
+			args.kinds = append(args.kinds, kindCronJob)
+		}
+	case "snapshot":
+		for _, kind := range args.kinds {
+			if kind != kindDeployment && kind != kindStatefulSet {
+				log.Fatalln(errors.New("error: snapshot only records the replicas of deployments and statefulsets"))
+			}
+		}
+	}
+
+	//A --selector flag can stand in for the target arguments
+	flagTargets := 0
+	if args.labelArg != "" {
+		flagTargets = 1
+	}
+
+	cmd := getCommand(osArgs)
 	args.cmd = cmd
+	if args.follow && (cmd != "getPodLogs" || args.output != "") {
+		log.Fatalln(errors.New("error: --follow only streams the plain output of getPodLogs"))
+	}
+	if args.merge && (cmd != "getPodLogs" || args.output != "" || args.follow) {
+		log.Fatalln(errors.New("error: --merge only interleaves the plain output of getPodLogs, and can't be combined with --follow"))
+	}
+	if args.sinceTimeArg != "" {
+		args.logs.sinceTime, err = time.Parse(time.RFC3339, args.sinceTimeArg)
+		if err != nil {
+			log.Fatalln(fmt.Errorf("error: --since-time must be an RFC3339 time like 2006-01-02T15:04:05Z: %v", err))
+		}
+	}
+	if err := checkLogOptions(args.logs, args.follow); err != nil {
+		log.Fatalln(err)
+	}
+
+	//Like grep, --before-context and --after-context take precedence over --context-lines
+	if args.beforeContext == 0 {
+		args.beforeContext = args.contextLines
+	}
+	if args.afterContext == 0 {
+		args.afterContext = args.contextLines
+	}
+	args.logs.filter, err = newLogFilter(args.grepArg, args.jsonFilterArg, args.beforeContext, args.afterContext)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	switch cmd {
 	case "getNumWithLabels", "getName":
-		if len(osArgs) < 4 {
+		if len(osArgs) < 4-flagTargets {
 			args.cmd = "error"
 			break
 		}
-		labels, err := convStringsToMap(osArgs[2 : len(osArgs)-1])
+		labelArgs := append([]string{}, osArgs[2:len(osArgs)-1]...)
+		if args.labelArg != "" {
+			labelArgs = append(labelArgs, args.labelArg)
+		}
+		selector, err := convStringsToSelector(labelArgs)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		args.labels = labels
+		args.selector = selector
 		args.namespace = osArgs[len(osArgs)-1]
 		args.scale = -1
-	case "getScale", "toggleOn", "toggleOff", "reset":
-		if len(osArgs) < 4 {
+	case "getScale", "toggleOn", "toggleOff", "reset", "suspend", "resume":
+		if len(osArgs) < 4-flagTargets {
 			args.cmd = "error"
 			break
 		}
 		err := error(nil)
-		args.labels, args.names, err = parseTargetArgs(osArgs[2 : len(osArgs)-1])
+		args.selector, args.names, err = parseTargetArgs(osArgs[2:len(osArgs)-1], args.labelArg)
 		if err != nil {
 			log.Fatalln(err)
 		}
 		args.namespace = osArgs[len(osArgs)-1]
 		args.scale = -1
 	case "setScale":
-		if len(osArgs) < 5 {
+		if len(osArgs) < 5-flagTargets {
 			args.cmd = "error"
 			break
 		}
@@ -478,17 +963,34 @@ func parseArgs(osArgs []string) kubeCmd {
 		if err != nil {
 			log.Fatalln(err)
 		}
-		args.labels, args.names, err = parseTargetArgs(osArgs[2 : len(osArgs)-2])
+		args.selector, args.names, err = parseTargetArgs(osArgs[2:len(osArgs)-2], args.labelArg)
 		if err != nil {
 			log.Fatalln(err)
 		}
 		args.namespace = osArgs[len(osArgs)-1]
 		args.scale = int32(scale)
+	case "snapshot":
+		if len(osArgs) != 3 {
+			args.cmd = "error"
+			break
+		}
+		args.namespace = osArgs[2]
+		args.scale = -1
+	case "restore":
+		if len(osArgs) != 3 {
+			args.cmd = "error"
+			break
+		}
+		args.file = osArgs[2]
+		args.scale = -1
 	case "getPodLogs", "getPodLifetimes":
 		if len(osArgs) != 4 {
 			args.cmd = "error"
 			break
 		}
+		if args.multiNamespace() {
+			log.Fatalln(errors.New("error: " + cmd + " targets a deployment in a single namespace"))
+		}
 		names := []string{osArgs[2]}
 
 		args.names = names
@@ -502,5 +1004,20 @@ func parseArgs(osArgs []string) kubeCmd {
 }
 
 func main() {
-	doCommand(parseArgs(os.Args))
+	args := parseArgs(os.Args)
+
+	//Only connects to kubernetes when the command actually needs a cluster
+	t := (*Toggler)(nil)
+	if args.cmd != "empty" && args.cmd != "error" {
+		toggler, err := NewTogglerFromConfig(args.client)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		toggler.dryRun = args.dryRun
+		toggler.force = args.force
+		toggler.concurrency = args.concurrency
+		toggler.verbose = args.verbose
+		t = toggler
+	}
+	doCommand(t, args)
 }
@@ -1,16 +1,170 @@
 package main
 
 import (
-	"os"
+	"bytes"
+	"context"
+	"io/ioutil"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 /*
-	In order for integration testing to work, there needs to be a deployment with the name testconnector-connector and labels "expose.name=usmc1", "expose.group=usmc" in the namespace defined below
+	The integration tests run against a fake clientset (k8s.io/client-go/kubernetes/fake). newFakeToggler seeds it with a deployment named
+	testconnector-connector with labels "expose.name=usmc1", "expose.group=usmc" in the namespace defined below, unless a test passes its own objects
 */
-var namespace = "jicd42dev"
+var namespace = "testnamespace"
+
+/* newDeployment returns a deployment fixture whose pods are selected by the label app=name and whose rollout has completed */
+func newDeployment(name string, namespace string, labels map[string]string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+		Status: convergedStatus(replicas),
+	}
+}
+
+/* convergedStatus returns the status the deployment controller reports once 'replicas' pods are updated and available */
+func convergedStatus(replicas int32) appsv1.DeploymentStatus {
+	return appsv1.DeploymentStatus{Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: replicas, AvailableReplicas: replicas}
+}
+
+/* newPod returns a pod fixture that belongs to the deployment with the given name and was created at 'created' */
+func newPod(name string, namespace string, deploymentName string, created time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			Labels:            map[string]string{"app": deploymentName},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+	}
+}
+
+/* defaultObjects returns the objects the integration tests expect to find in the cluster */
+func defaultObjects() []runtime.Object {
+	return []runtime.Object{
+		newDeployment("testconnector-connector", namespace, map[string]string{"expose.name": "usmc1", "expose.group": "usmc"}, 1),
+		newDeployment("otherconnector-connector", namespace, map[string]string{"expose.name": "usmc2", "expose.group": "usmc"}, 2),
+	}
+}
+
+/* scaleOf returns the scale of the named workload in getScale results, or an empty string if it is not among them */
+func scaleOf(results scaleResults, name string) string {
+	for _, r := range results {
+		if r.Name == name {
+			return r.scale()
+		}
+	}
+	return ""
+}
+
+/* lifetimeOf returns the lifetime of the named pod in getPodLifetimes results, or an empty string if it is not among them */
+func lifetimeOf(results podLifetimeResults, name string) string {
+	for _, r := range results {
+		if r.Pod == name {
+			return r.Lifetime
+		}
+	}
+	return ""
+}
+
+/* newStatefulSet returns a statefulset fixture whose pods are selected by the label app=name and whose rollout has completed */
+func newStatefulSet(name string, namespace string, labels map[string]string, replicas int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+		Status: appsv1.StatefulSetStatus{Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: replicas},
+	}
+}
+
+/* newFakeToggler returns a Toggler backed by a fake clientset seeded with the given objects (or defaultObjects if none are given).
+   The fake clientset does not implement the scale subresource, so reactors are added that read and write the replicas of the seeded
+   deployments and statefulsets. A scale update also sets the workload's status as if the controller converged instantly */
+func newFakeToggler(objects ...runtime.Object) (*Toggler, *fake.Clientset) {
+	if len(objects) == 0 {
+		objects = defaultObjects()
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	for _, resource := range []string{"deployments", "statefulsets"} {
+		gvr := appsv1.SchemeGroupVersion.WithResource(resource)
+
+		clientset.PrependReactor("get", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "scale" {
+				return false, nil, nil
+			}
+			obj, err := clientset.Tracker().Get(gvr, action.GetNamespace(), action.(k8stesting.GetAction).GetName())
+			if err != nil {
+				return true, nil, err
+			}
+			meta := obj.(metav1.Object)
+			replicas := int32(0)
+			switch workload := obj.(type) {
+			case *appsv1.Deployment:
+				replicas = *workload.Spec.Replicas
+			case *appsv1.StatefulSet:
+				replicas = *workload.Spec.Replicas
+			}
+			return true, &autoscalingv1.Scale{
+				ObjectMeta: metav1.ObjectMeta{Name: meta.GetName(), Namespace: meta.GetNamespace()},
+				Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+			}, nil
+		})
+		clientset.PrependReactor("update", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "scale" {
+				return false, nil, nil
+			}
+			scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+			obj, err := clientset.Tracker().Get(gvr, action.GetNamespace(), scale.Name)
+			if err != nil {
+				return true, nil, err
+			}
+			replicas := scale.Spec.Replicas
+			switch workload := obj.DeepCopyObject().(type) {
+			case *appsv1.Deployment:
+				workload.Spec.Replicas = &replicas
+				workload.Status = convergedStatus(replicas)
+				obj = workload
+			case *appsv1.StatefulSet:
+				workload.Spec.Replicas = &replicas
+				workload.Status = appsv1.StatefulSetStatus{Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: replicas}
+				obj = workload
+			}
+			if err := clientset.Tracker().Update(gvr, obj, action.GetNamespace()); err != nil {
+				return true, nil, err
+			}
+			return true, scale, nil
+		})
+	}
+	toggler := NewToggler(clientset)
+	toggler.out = ioutil.Discard
+	return toggler, clientset
+}
+
+/* newBufferedToggler returns a fake Toggler seeded like newFakeToggler whose output is written to the returned buffer */
+func newBufferedToggler(objects ...runtime.Object) (*Toggler, *fake.Clientset, *bytes.Buffer) {
+	toggler, clientset := newFakeToggler(objects...)
+	out := new(bytes.Buffer)
+	toggler.out = out
+	return toggler, clientset, out
+}
 
 /*
 	Unit test checkMap
@@ -26,6 +180,15 @@ func TestCheckMap_Map3Vals(t *testing.T) {
 	}
 }
 
+//Test for set-based and negated selector expressions. Should return true
+func TestCheckMap_SelectorExpressions(t *testing.T) {
+	mapArray := []string{"tier in (web,api)", "env!=prod", "!canary"}
+	isMap, err := checkMap(mapArray)
+	if !isMap || err != nil {
+		t.Errorf("Returned incorrect bool or error for %v, got: %t, want: true, error: %v", mapArray, isMap, err)
+	}
+}
+
 //Test for 0 values. Should return an error
 func TestCheckMap_0Vals(t *testing.T) {
 	mapArray := []string{}
@@ -91,54 +254,71 @@ func TestCheckMap_InvalidLabels4(t *testing.T) {
 }
 
 /*
-	Unit test convStringsToMap
+	Unit test convStringsToSelector
 */
 
-func TestConvStringsToMap_ValidMapInput(t *testing.T) {
-	testMap := map[string]string{"foo": "one", "bar": "two"}
+func TestConvStringsToSelector_ValidMapInput(t *testing.T) {
+	testSelector := labels.SelectorFromSet(map[string]string{"foo": "one", "bar": "two"})
 	testArr := []string{"foo=one", "bar=two"}
-	inputMap, err := convStringsToMap(testArr)
-	if !reflect.DeepEqual(testMap, inputMap) || err != nil {
-		t.Errorf("Returned incorrectly formatted map or error for %v, got: %v, want: %v, error: %v", testArr, inputMap, testMap, err)
+	inputSelector, err := convStringsToSelector(testArr)
+	if err != nil || inputSelector.String() != testSelector.String() {
+		t.Errorf("Returned incorrect selector or error for %v, got: %v, want: %v, error: %v", testArr, inputSelector, testSelector, err)
+	}
+}
+
+//Test for set-based and existence expressions. Should match only the label sets that satisfy all of them
+func TestConvStringsToSelector_SetBasedInput(t *testing.T) {
+	testArr := []string{"tier in (web,api)", "env!=prod", "!canary", "team"}
+	inputSelector, err := convStringsToSelector(testArr)
+	if err != nil {
+		t.Fatalf("Returned error for %v, error: %v", testArr, err)
+	}
+	matches := map[string]labels.Set{
+		"web in dev":      {"tier": "web", "env": "dev", "team": "a"},
+		"api without env": {"tier": "api", "team": "a"},
+	}
+	misses := map[string]labels.Set{
+		"wrong tier": {"tier": "db", "env": "dev", "team": "a"},
+		"prod":       {"tier": "web", "env": "prod", "team": "a"},
+		"canary":     {"tier": "web", "canary": "true", "team": "a"},
+		"no team":    {"tier": "web"},
+	}
+	for name, set := range matches {
+		if !inputSelector.Matches(set) {
+			t.Errorf("Expected %v to match %v (%v)", inputSelector, set, name)
+		}
+	}
+	for name, set := range misses {
+		if inputSelector.Matches(set) {
+			t.Errorf("Expected %v not to match %v (%v)", inputSelector, set, name)
+		}
 	}
 }
 
 //Test for an empty string argument, should return error
-func TestConvStringsToMap_InValidMapInput1(t *testing.T) {
+func TestConvStringsToSelector_InValidMapInput1(t *testing.T) {
 	testArr := []string{"foo=one", "bar="}
-	inputMap, err := convStringsToMap(testArr)
+	inputSelector, err := convStringsToSelector(testArr)
 	if err == nil {
-		t.Errorf("Expected error for %v, got: %v, error: %v", testArr, inputMap, err)
+		t.Errorf("Expected error for %v, got: %v, error: %v", testArr, inputSelector, err)
 	}
 }
 
-//Test for an invalid map format, should return error
-func TestConvStringsToMap_InValidMapInput2(t *testing.T) {
-	testArr := []string{"foo", "bar"}
-	inputMap, err := convStringsToMap(testArr)
+//Test for an invalid selector expression, should return error
+func TestConvStringsToSelector_InValidMapInput2(t *testing.T) {
+	testArr := []string{"tier in (web", "bar"}
+	inputSelector, err := convStringsToSelector(testArr)
 	if err == nil {
-		t.Errorf("Expected error for %v, got: %v, error: %v", testArr, inputMap, err)
+		t.Errorf("Expected error for %v, got: %v, error: %v", testArr, inputSelector, err)
 	}
 }
 
-//Test for an empty input map, should return an empty map
-func TestConvStringsToMap_EmptyInput(t *testing.T) {
-	testMap := map[string]string{}
+//Test for an empty input array, should return a selector that matches everything
+func TestConvStringsToSelector_EmptyInput(t *testing.T) {
 	testArr := []string{}
-	inputMap, err := convStringsToMap(testArr)
-	if !reflect.DeepEqual(testMap, inputMap) || err != nil {
-		t.Errorf("Expected error for %v, got: %v, error: %v", testArr, inputMap, err)
-	}
-}
-
-/*
-	Integration test initClientSet
-*/
-//Tests to confirm there is a .kube/config file
-func TestInitClientSet(t *testing.T) {
-	_, err := os.Stat(".kube/config")
-	if os.IsNotExist(err) {
-		t.Errorf("Config file in local .kube directory does not exist")
+	inputSelector, err := convStringsToSelector(testArr)
+	if err != nil || !inputSelector.Empty() {
+		t.Errorf("Expected empty selector for %v, got: %v, error: %v", testArr, inputSelector, err)
 	}
 }
 
@@ -148,9 +328,10 @@ func TestInitClientSet(t *testing.T) {
 
 //Tests GetDeploymentNamesWithLabels using label for specific connector. Should return that connector name
 func TestGetDeploymentNamesWithLabels_ExistingLabels(t *testing.T) {
-	usmcLabel := map[string]string{"expose.name": "usmc1"}
+	toggler, _ := newFakeToggler()
+	usmcLabel := labels.SelectorFromSet(map[string]string{"expose.name": "usmc1"})
 	usmcName := []string{"testconnector-connector"}
-	nameLocal, err := GetDeploymentNamesWithLabels(usmcLabel, namespace)
+	nameLocal, err := toggler.GetDeploymentNamesWithLabels(usmcLabel, namespace)
 	if err != nil || !reflect.DeepEqual(nameLocal, usmcName) {
 		t.Errorf("Returned incorrectly names for %v, got: %v, want: %v, error: %v", usmcLabel, nameLocal, usmcName, err)
 	}
@@ -158,9 +339,10 @@ func TestGetDeploymentNamesWithLabels_ExistingLabels(t *testing.T) {
 
 //Tests GetDeploymentNamesWithLabels using labels for specific connector. Should return that connector name
 func TestGetDeploymentNamesWithLabels_MultipleExistingLabels(t *testing.T) {
-	usmcLabel := map[string]string{"expose.name": "usmc1", "expose.group": "usmc"}
+	toggler, _ := newFakeToggler()
+	usmcLabel := labels.SelectorFromSet(map[string]string{"expose.name": "usmc1", "expose.group": "usmc"})
 	usmcName := []string{"testconnector-connector"}
-	nameLocal, err := GetDeploymentNamesWithLabels(usmcLabel, namespace)
+	nameLocal, err := toggler.GetDeploymentNamesWithLabels(usmcLabel, namespace)
 	if err != nil || !reflect.DeepEqual(nameLocal, usmcName) {
 		t.Errorf("Returned incorrectly names for %v, got: %v, want: %v, error: %v", usmcLabel, nameLocal, usmcName, err)
 	}
@@ -168,22 +350,35 @@ func TestGetDeploymentNamesWithLabels_MultipleExistingLabels(t *testing.T) {
 
 //Tests GetDeploymentNamesWithLabels using non existing labels. Should return an error
 func TestGetDeploymentNamesWithLabels_NonExistingLabels(t *testing.T) {
-	nonExistentLabel := map[string]string{"expose.type": "test"}
-	nameLocal, err := GetDeploymentNamesWithLabels(nonExistentLabel, namespace)
+	toggler, _ := newFakeToggler()
+	nonExistentLabel := labels.SelectorFromSet(map[string]string{"expose.type": "test"})
+	nameLocal, err := toggler.GetDeploymentNamesWithLabels(nonExistentLabel, namespace)
 	if err == nil {
 		t.Errorf("Expected error for %v, got: %v, error: %v", nonExistentLabel, nameLocal, err)
 	}
 }
 
+//Tests GetDeploymentNamesWithLabels with a set-based selector. Should return only the deployments it matches
+func TestGetDeploymentNamesWithLabels_SetBasedSelector(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	selector, _ := labels.Parse("expose.group=usmc,expose.name notin (usmc1)")
+	exOut := []string{"otherconnector-connector"}
+	nameLocal, err := toggler.GetDeploymentNamesWithLabels(selector, namespace)
+	if err != nil || !reflect.DeepEqual(nameLocal, exOut) {
+		t.Errorf("Returned incorrect names for %v, got: %v, want: %v, error: %v", selector, nameLocal, exOut, err)
+	}
+}
+
 /*
 	Integration test getNames
 */
 
 //Tests GetNames with labels for a specific connector and no name input. Should return that connector name
 func TestGetNames_LabelsMap(t *testing.T) {
-	usmcLabel := map[string]string{"expose.name": "usmc1"}
+	toggler, _ := newFakeToggler()
+	usmcLabel := labels.SelectorFromSet(map[string]string{"expose.name": "usmc1"})
 	usmcName := []string{"testconnector-connector"}
-	nameLocal, err := getNames(usmcLabel, nil, namespace)
+	nameLocal, err := toggler.getNames(kindDeployment, usmcLabel, nil, namespace)
 	if err != nil || !reflect.DeepEqual(nameLocal, usmcName) {
 		t.Errorf("Returned incorrectly names for %v, got: %v, want: %v, error: %v", usmcLabel, nameLocal, usmcName, err)
 	}
@@ -191,8 +386,9 @@ func TestGetNames_LabelsMap(t *testing.T) {
 
 //Tests GetNames with no label input and name input. Should just return the inputed name
 func TestGetNames_NamesArray(t *testing.T) {
+	toggler, _ := newFakeToggler()
 	usmcName := []string{"testconnector-connector"}
-	nameLocal, err := getNames(nil, usmcName, namespace)
+	nameLocal, err := toggler.getNames(kindDeployment, nil, usmcName, namespace)
 	if err != nil || !reflect.DeepEqual(nameLocal, usmcName) {
 		t.Errorf("Returned incorrectly names for %v, got: %v, want: %v, error: %v", usmcName, nameLocal, usmcName, err)
 	}
@@ -200,9 +396,10 @@ func TestGetNames_NamesArray(t *testing.T) {
 
 //Tests GetNames with label and name input. Should ignore the labels and return the inputed name
 func TestGetNames_NamesAndLabelsArray(t *testing.T) {
-	usmcLabel := map[string]string{"expose.name": "usmc1"}
+	toggler, _ := newFakeToggler()
+	usmcLabel := labels.SelectorFromSet(map[string]string{"expose.name": "usmc1"})
 	usmcName := []string{"testconnector-connector"}
-	nameLocal, err := getNames(usmcLabel, usmcName, namespace)
+	nameLocal, err := toggler.getNames(kindDeployment, usmcLabel, usmcName, namespace)
 	if err != nil || !reflect.DeepEqual(nameLocal, usmcName) {
 		t.Errorf("Returned incorrectly names for %v, got: %v, want: %v, error: %v", usmcName, nameLocal, usmcName, err)
 	}
@@ -210,8 +407,9 @@ func TestGetNames_NamesAndLabelsArray(t *testing.T) {
 
 //Tests GetNames with labels that don't exist and no name input
 func TestGetNames_NonExistentLabelsAndNames(t *testing.T) {
-	nonExistentLabel := map[string]string{"expose.type": "test"}
-	nameLocal, err := GetDeploymentNamesWithLabels(nonExistentLabel, namespace)
+	toggler, _ := newFakeToggler()
+	nonExistentLabel := labels.SelectorFromSet(map[string]string{"expose.type": "test"})
+	nameLocal, err := toggler.GetDeploymentNamesWithLabels(nonExistentLabel, namespace)
 	if err == nil {
 		t.Errorf("Expected error for %v, got: %v, error: %v", nonExistentLabel, nameLocal, err)
 	}
@@ -224,10 +422,11 @@ func TestGetNames_NonExistentLabelsAndNames(t *testing.T) {
 //Tests GetDeploymentScale and SetDeploymentScale by using the connector name to set the deployment scale of testconnector-connector
 //to scale 3 and getting the new scale to make sure the values match
 func TestGetAndSetDeployment_ByName(t *testing.T) {
+	toggler, _ := newFakeToggler()
 	testScale := 3
-	_, err1 := SetDeploymentScales(nil, []string{"testconnector-connector"}, int32(testScale), namespace)
-	out, err2 := GetDeploymentScales(nil, []string{"testconnector-connector"}, namespace)
-	outInt, err3 := strconv.ParseInt(out["testconnector-connector"], 10, 64)
+	_, err1 := toggler.SetDeploymentScales(nil, []string{"testconnector-connector"}, int32(testScale), namespace)
+	out, err2 := toggler.GetDeploymentScales(nil, []string{"testconnector-connector"}, namespace)
+	outInt, err3 := strconv.ParseInt(scaleOf(out, "testconnector-connector"), 10, 64)
 	if err1 != nil || err3 != nil || outInt != int64(testScale) {
 		t.Errorf("Returned incorrect scale for set input %v, got: %v, setDeploymentScalesError: %v, getDeploymentScalesError: %v, parseReturnError: %v", testScale, outInt, err1, err2, err3)
 	}
@@ -236,10 +435,11 @@ func TestGetAndSetDeployment_ByName(t *testing.T) {
 //Tests GetDeploymentScale and SetDeploymentScale by using the connector labels to set the deployment scale of testconnector-connector
 //to scale 1 and getting the new scale to make sure the values match
 func TestGetAndSetDeployment_ByLabel(t *testing.T) {
+	toggler, _ := newFakeToggler()
 	testScale := 1
-	_, err1 := SetDeploymentScales(map[string]string{"expose.name": "usmc1"}, nil, int32(testScale), namespace)
-	out, err2 := GetDeploymentScales(map[string]string{"expose.name": "usmc1"}, nil, namespace)
-	outInt, err3 := strconv.ParseInt(out["testconnector-connector"], 10, 64)
+	_, err1 := toggler.SetDeploymentScales(labels.SelectorFromSet(map[string]string{"expose.name": "usmc1"}), nil, int32(testScale), namespace)
+	out, err2 := toggler.GetDeploymentScales(labels.SelectorFromSet(map[string]string{"expose.name": "usmc1"}), nil, namespace)
+	outInt, err3 := strconv.ParseInt(scaleOf(out, "testconnector-connector"), 10, 64)
 	if err1 != nil || err2 != nil || err3 != nil || outInt != int64(testScale) {
 		t.Errorf("Returned incorrect scale for set input %v, got: %v, setDeploymentScalesError: %v, getDeploymentScalesError: %v, parseReturnError: %v", testScale, outInt, err1, err2, err3)
 	}
@@ -247,10 +447,11 @@ func TestGetAndSetDeployment_ByLabel(t *testing.T) {
 
 //Tests GetDeploymentScale and SetDeploymentScale by requesting a deployment with nil labels and nil names. Should return an error
 func TestGetAndSetDeployment_NonExistentNameOrLabel(t *testing.T) {
+	toggler, _ := newFakeToggler()
 	testScale := 5
-	_, err1 := SetDeploymentScales(nil, nil, int32(testScale), namespace)
-	out, err2 := GetDeploymentScales(nil, nil, namespace)
-	outInt, err3 := strconv.ParseInt(out["testconnector-connector"], 10, 64)
+	_, err1 := toggler.SetDeploymentScales(nil, nil, int32(testScale), namespace)
+	out, err2 := toggler.GetDeploymentScales(nil, nil, namespace)
+	outInt, err3 := strconv.ParseInt(scaleOf(out, "testconnector-connector"), 10, 64)
 	if err1 == nil || err2 == nil || err3 == nil {
 		t.Errorf("Expected 3 errors for nil input but returned less than 3 for set input %v, got: %v, setDeploymentScalesError: %v, getDeploymentScalesError: %v, parseReturnError: %v", testScale, outInt, err1, err2, err3)
 	}
@@ -262,8 +463,9 @@ func TestGetAndSetDeployment_NonExistentNameOrLabel(t *testing.T) {
 
 //Tests GetNumDeploymentsWithLabels by requesting the number of deployments with the label "expose.name:usmc1" which should equal 1
 func TestGetNumDeploymentsWithLabels_1(t *testing.T) {
+	toggler, _ := newFakeToggler()
 	exOut := 1
-	out, err := GetNumDeploymentsWithLabels(map[string]string{"expose.name": "usmc1"}, namespace)
+	out, err := toggler.GetNumDeploymentsWithLabels(labels.SelectorFromSet(map[string]string{"expose.name": "usmc1"}), namespace)
 	if err != nil || out != exOut {
 		t.Errorf("Return incorrect number of deployments with label 'expose.name: usmc1' or return an error. expected: %v, got: %v, error: %v", exOut, out, err)
 	}
@@ -271,9 +473,142 @@ func TestGetNumDeploymentsWithLabels_1(t *testing.T) {
 
 //Tests GetNumDeploymentsWithLabels by requesting the number of deployments with a label that is not used which should equal 0
 func TestGetNumDeploymentsWithLabels_0(t *testing.T) {
+	toggler, _ := newFakeToggler()
 	exOut := 0
-	out, err := GetNumDeploymentsWithLabels(map[string]string{"expose.type": "test"}, namespace)
+	out, err := toggler.GetNumDeploymentsWithLabels(labels.SelectorFromSet(map[string]string{"expose.type": "test"}), namespace)
 	if err != nil || out != exOut {
 		t.Errorf("Return incorrect number of deployments with label 'expose.name: usmc1' or return an error. expected: %v, got: %v, error: %v", exOut, out, err)
 	}
 }
+
+/*
+	Integration test GetPodCreationTimestamps, GetPodLifetimes and GetPodLogs
+*/
+
+//Tests GetPodCreationTimestamps with two pods in a deployment. Should return each pod's formatted creation time
+func TestGetPodCreationTimestamps(t *testing.T) {
+	created := time.Date(2021, 5, 10, 12, 30, 0, 0, time.UTC)
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 2),
+		newPod("testconnector-connector-a", namespace, "testconnector-connector", created),
+		newPod("testconnector-connector-b", namespace, "testconnector-connector", created.Add(time.Hour)),
+		newPod("otherconnector-connector-a", namespace, "otherconnector-connector", created),
+	)
+	exOut := map[string]string{"testconnector-connector-a": "2021-05-10|12:30 UTC", "testconnector-connector-b": "2021-05-10|13:30 UTC"}
+	out, err := toggler.GetPodCreationTimestamps("testconnector-connector", namespace)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect timestamps, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests GetPodLifetimes with a pod created two hours ago. Should return a lifetime of at least two hours
+func TestGetPodLifetimes(t *testing.T) {
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newPod("testconnector-connector-a", namespace, "testconnector-connector", time.Now().Add(-2*time.Hour)),
+	)
+	out, err := toggler.GetPodLifetimes("testconnector-connector", namespace)
+	lifetime, parseErr := time.ParseDuration(lifetimeOf(out, "testconnector-connector-a"))
+	if err != nil || parseErr != nil || lifetime < time.Hour {
+		t.Errorf("Returned incorrect lifetime, got: %v, error: %v, parseError: %v", out, err, parseErr)
+	}
+}
+
+//Tests GetPodLifetimes with a pod whose two containers restarted. Should report the sum of their restart counts
+func TestGetPodLifetimes_Restarts(t *testing.T) {
+	pod := newPod("testconnector-connector-a", namespace, "testconnector-connector", time.Now())
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "app", RestartCount: 2}, {Name: "sidecar", RestartCount: 1}}
+	toggler, _ := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 1), pod)
+	out, err := toggler.GetPodLifetimes("testconnector-connector", namespace)
+	if err != nil || len(out) != 1 || out[0].Restarts != 3 {
+		t.Errorf("Returned incorrect restarts, got: %+v, want: 3, error: %v", out, err)
+	}
+}
+
+//Tests GetPodLogs against the fake clientset, which answers every log request with "fake logs"
+func TestGetPodLogs(t *testing.T) {
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newPod("testconnector-connector-a", namespace, "testconnector-connector", time.Now()),
+	)
+	exOut := map[string]string{"testconnector-connector-a": "fake logs"}
+	out, err := toggler.GetPodLogs("testconnector-connector", namespace)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect logs, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests GetPodLogs with a deployment that does not exist. Should return an error
+func TestGetPodLogs_NonExistentDeployment(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	out, err := toggler.GetPodLogs("missing-connector", namespace)
+	if err == nil {
+		t.Errorf("Expected error for missing deployment, got: %v, error: %v", out, err)
+	}
+}
+
+/*
+	Integration test listDeployments
+*/
+
+/* recordingClientset wraps the fake clientset so that listing deployments records the ListOptions each request was sent with and
+   answers with the page of its continue token */
+type recordingClientset struct {
+	*fake.Clientset
+	deployments *recordingDeployments
+}
+
+func (c recordingClientset) AppsV1() appsv1client.AppsV1Interface {
+	return recordingAppsV1{c.Clientset.AppsV1(), c.deployments}
+}
+
+type recordingAppsV1 struct {
+	appsv1client.AppsV1Interface
+	deployments *recordingDeployments
+}
+
+func (a recordingAppsV1) Deployments(namespace string) appsv1client.DeploymentInterface {
+	return a.deployments
+}
+
+type recordingDeployments struct {
+	appsv1client.DeploymentInterface
+	pages    map[string]*appsv1.DeploymentList
+	requests []metav1.ListOptions
+}
+
+func (d *recordingDeployments) List(ctx context.Context, opts metav1.ListOptions) (*appsv1.DeploymentList, error) {
+	d.requests = append(d.requests, opts)
+	return d.pages[opts.Continue], nil
+}
+
+//Tests that the selector and page size are sent to the API server and that every page of a paginated list is collected
+func TestListDeployments_ServerSideSelectorAndPages(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	deployments := &recordingDeployments{
+		pages: map[string]*appsv1.DeploymentList{
+			"": {
+				ListMeta: metav1.ListMeta{Continue: "page2"},
+				Items:    []appsv1.Deployment{*newDeployment("a", namespace, map[string]string{"expose.group": "usmc"}, 1)},
+			},
+			"page2": {
+				Items: []appsv1.Deployment{*newDeployment("b", namespace, map[string]string{"expose.group": "usmc"}, 1)},
+			},
+		},
+	}
+	toggler := NewToggler(recordingClientset{clientset, deployments})
+
+	selector := labels.SelectorFromSet(map[string]string{"expose.group": "usmc"})
+	listed, err := toggler.listDeployments(selector, namespace)
+	names := []string{}
+	for _, deployment := range listed {
+		names = append(names, deployment.Name)
+	}
+	exRequests := []metav1.ListOptions{
+		{LabelSelector: "expose.group=usmc", Limit: listPageSize},
+		{LabelSelector: "expose.group=usmc", Limit: listPageSize, Continue: "page2"},
+	}
+	if err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) || !reflect.DeepEqual(deployments.requests, exRequests) {
+		t.Errorf("Listed incorrectly, got names: %v, requests: %+v, want requests: %+v, error: %v", names, deployments.requests, exRequests, err)
+	}
+}
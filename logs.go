@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/* podPollInterval is how often --follow looks for new pods of the deployment, such as the ones a rollout creates */
+var podPollInterval = 2 * time.Second
+
+/* logColors are the ANSI colors the "[pod/container]" prefixes of --follow cycle through, one per pod, when the output is a terminal */
+var logColors = []string{"\x1b[36m", "\x1b[33m", "\x1b[32m", "\x1b[35m", "\x1b[34m", "\x1b[31m"}
+
+/* colorReset ends an ANSI color */
+const colorReset = "\x1b[0m"
+
+/* isTerminal returns true if w is a terminal rather than a file, a pipe or a buffer */
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+/* logOptions choose which containers of a pod getPodLogs reads and which part of each log, mirroring the kubectl logs flags of
+   the same names. A tail of -1 reads the whole log. The lines read are then narrowed down by filter, if it is set */
+type logOptions struct {
+	since         time.Duration
+	sinceTime     time.Time
+	tail          int64
+	timestamps    bool
+	previous      bool
+	container     string
+	allContainers bool
+	filter        *logFilter
+}
+
+/* allLogs are the logOptions of GetPodLogs, the whole current log of every container */
+var allLogs = logOptions{tail: -1}
+
+/* checkLogOptions returns an error if the log flags contradict each other, or can't be combined with --follow */
+func checkLogOptions(o logOptions, follow bool) error {
+	switch {
+	case o.since < 0:
+		return errors.New("error: --since must not be negative")
+	case o.since != 0 && !o.sinceTime.IsZero():
+		return errors.New("error: only one of --since and --since-time can be used")
+	case o.tail < -1:
+		return errors.New("error: --tail must be -1 (the whole log) or more")
+	case o.container != "" && o.allContainers:
+		return errors.New("error: only one of --container and --all-containers can be used")
+	case o.previous && follow:
+		return errors.New("error: --previous reads the log of a container that has stopped, which can't be followed")
+	}
+	return nil
+}
+
+/* podLogOptions returns the corev1.PodLogOptions that read the log of the given container. --since is rounded up to whole seconds
+   the way kubectl does */
+func (o logOptions) podLogOptions(container string, follow bool) *corev1.PodLogOptions {
+	opts := &corev1.PodLogOptions{Container: container, Follow: follow, Timestamps: o.timestamps, Previous: o.previous}
+	if o.since > 0 {
+		seconds := int64(math.Ceil(o.since.Seconds()))
+		opts.SinceSeconds = &seconds
+	}
+	if !o.sinceTime.IsZero() {
+		sinceTime := metav1.NewTime(o.sinceTime)
+		opts.SinceTime = &sinceTime
+	}
+	if o.tail >= 0 {
+		tail := o.tail
+		opts.TailLines = &tail
+	}
+	return opts
+}
+
+/* containers returns the names of the containers of the pod whose logs are read: the one named with --container, or every regular
+   container, preceded by the init containers with --all-containers */
+func (o logOptions) containers(pod corev1.Pod) []string {
+	if o.container != "" {
+		return []string{o.container}
+	}
+	specs := pod.Spec.Containers
+	if o.allContainers {
+		specs = append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	}
+
+	//A pod without containers in its spec gets the single container "", which lets the API server pick the pod's only container
+	if len(specs) == 0 {
+		return []string{""}
+	}
+
+	//With --previous a container that never restarted has no previous log, so it is left out unless it was named
+	restarted := make(map[string]bool)
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		restarted[status.Name] = status.RestartCount > 0 || status.LastTerminationState.Terminated != nil
+	}
+	names := []string{}
+	for _, c := range specs {
+		if o.previous && !restarted[c.Name] {
+			continue
+		}
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+/* containerStatuses returns the statuses of the containers of the pod whose logs --follow streams, chosen the same way as containers */
+func (o logOptions) containerStatuses(pod corev1.Pod) []corev1.ContainerStatus {
+	statuses := pod.Status.ContainerStatuses
+	if o.allContainers || o.container != "" {
+		statuses = append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	}
+	if o.container == "" {
+		return statuses
+	}
+	for _, status := range statuses {
+		if status.Name == o.container {
+			return []corev1.ContainerStatus{status}
+		}
+	}
+	return nil
+}
+
+/* logPrefixes build the "[pod/container] " prefixes of log lines from several containers. When the output is a terminal every pod
+   gets the next of the logColors, shared by all of its containers */
+type logPrefixes struct {
+	color     bool
+	podColors map[string]string
+}
+
+func newLogPrefixes(out io.Writer) *logPrefixes {
+	return &logPrefixes{color: isTerminal(out), podColors: map[string]string{}}
+}
+
+func (p *logPrefixes) prefix(pod string, container string) string {
+	source := podLogResult{Pod: pod, Container: container}.source()
+	if !p.color {
+		return "[" + source + "] "
+	}
+	if _, ok := p.podColors[pod]; !ok {
+		p.podColors[pod] = logColors[len(p.podColors)%len(logColors)]
+	}
+	return p.podColors[pod] + "[" + source + "]" + colorReset + " "
+}
+
+/* lineWriter writes whole lines to w under a lock, so that lines read from concurrent log streams never interleave */
+type lineWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lineWriter) writeLine(prefix string, line string) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	fmt.Fprintf(lw.w, "%s%s\n", prefix, line)
+}
+
+/* logStream is a container whose log --follow is streaming or has streamed, with the container's restart count when it started */
+type logStream struct {
+	active   bool
+	restarts int32
+}
+
+/* started returns true once a container has a log to read, that is once it is running or has run */
+func started(status corev1.ContainerStatus) bool {
+	return status.State.Running != nil || status.State.Terminated != nil
+}
+
+/* FollowPodLogs streams the logs of the containers chosen by opts of every pod of the given deployment to the Toggler's output until
+   ctx is done, prefixing each line with "[pod/container] " */
+func (t *Toggler) FollowPodLogs(ctx context.Context, deploymentName string, namespace string, opts logOptions) error {
+	out := &lineWriter{w: t.out}
+	prefixes := newLogPrefixes(t.out)
+
+	mu := sync.Mutex{}
+	streams := map[string]*logStream{}
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	//The pods are listed again every podPollInterval, so that pods created during a rollout are picked up
+	for {
+		pods, err := t.getPods(deploymentName, namespace)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		mu.Lock()
+		for _, pod := range pods {
+			for _, status := range opts.containerStatuses(pod) {
+				key := pod.Name + "/" + status.Name
+				s, seen := streams[key]
+
+				//A container that restarted is streamed again from the start of its new log, limited by opts like the first one
+				if !started(status) || (seen && (s.active || status.RestartCount <= s.restarts)) {
+					continue
+				}
+				s = &logStream{active: true, restarts: status.RestartCount}
+				streams[key] = s
+
+				prefix := prefixes.prefix(pod.Name, status.Name)
+				wg.Add(1)
+				go func(podName string, container string, prefix string, key string, s *logStream) {
+					defer wg.Done()
+					err := t.streamLog(ctx, podName, container, namespace, opts, prefix, out)
+					mu.Lock()
+					defer mu.Unlock()
+					s.active = false
+					switch {
+					//The API server answers 400 Bad Request while the container is still starting, so it is tried again on the next poll
+					case apierrors.IsBadRequest(err):
+						delete(streams, key)
+					//Any other error, such as Forbidden, won't go away by polling, so it is printed once
+					case err != nil && ctx.Err() == nil:
+						out.writeLine(prefix, "error: "+err.Error())
+					}
+				}(pod.Name, status.Name, prefix, key, s)
+			}
+		}
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(podPollInterval):
+		}
+	}
+}
+
+/* streamLog follows the log of a single container from the part chosen by opts, writing each line that passes the filter of opts to
+   out with the given prefix until the log ends or ctx is done. It only returns an error if the log couldn't be opened */
+func (t *Toggler) streamLog(ctx context.Context, podName string, container string, namespace string, opts logOptions, prefix string, out *lineWriter) error {
+	stream, err := t.clientset.CoreV1().Pods(namespace).GetLogs(podName, opts.podLogOptions(container, true)).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	filter := opts.filter.newContext()
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			for _, l := range filter.next(strings.TrimSuffix(line, "\n")) {
+				out.writeLine(prefix, l)
+			}
+		}
+
+		//io.EOF once the container stops or the pod is deleted, or an error once ctx is done
+		if err != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+func init() {
+	podPollInterval = 10 * time.Millisecond
+}
+
+/* newRunningPod returns a pod fixture of the given deployment whose containers are all running */
+func newRunningPod(name string, deploymentName string, containers ...string) *corev1.Pod {
+	pod := newPod(name, namespace, deploymentName, time.Now())
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:  c,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+	}
+	return pod
+}
+
+/* syncBuffer is a bytes.Buffer that can be written by FollowPodLogs while a test reads it */
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+/* sortedLines returns the lines written to the buffer so far, sorted, since concurrent streams write them in any order */
+func (b *syncBuffer) sortedLines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := strings.Split(strings.TrimSuffix(b.buf.String(), "\n"), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+/* followFor runs FollowPodLogs on testconnector-connector for the given duration, calling during() while it runs */
+func followFor(t *testing.T, toggler *Toggler, d time.Duration, during func()) *syncBuffer {
+	out := &syncBuffer{}
+	toggler.out = out
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- toggler.FollowPodLogs(ctx, "testconnector-connector", namespace, allLogs)
+	}()
+	if during != nil {
+		time.Sleep(d / 3)
+		during()
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Followed logs incorrectly, error: %v", err)
+	}
+	return out
+}
+
+/* failingLogsClientset wraps the fake clientset so that every log request fails with the given HTTP status, counting the requests */
+type failingLogsClientset struct {
+	*fake.Clientset
+	status   int
+	requests *int32
+}
+
+func (c failingLogsClientset) CoreV1() corev1client.CoreV1Interface {
+	return failingLogsCoreV1{c.Clientset.CoreV1(), c}
+}
+
+type failingLogsCoreV1 struct {
+	corev1client.CoreV1Interface
+	c failingLogsClientset
+}
+
+func (c failingLogsCoreV1) Pods(namespace string) corev1client.PodInterface {
+	return failingLogsPods{c.CoreV1Interface.Pods(namespace), c.c}
+}
+
+type failingLogsPods struct {
+	corev1client.PodInterface
+	c failingLogsClientset
+}
+
+func (p failingLogsPods) GetLogs(name string, opts *corev1.PodLogOptions) *rest.Request {
+	atomic.AddInt32(p.c.requests, 1)
+	client := &fakerest.RESTClient{
+		Client: fakerest.CreateHTTPClient(func(request *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: p.c.status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}),
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         corev1.SchemeGroupVersion,
+	}
+	return client.Request()
+}
+
+/*
+	Integration test FollowPodLogs
+*/
+
+//Tests following a deployment whose pods come and go. Should stream every started container once, prefixed, and pick up the new pod
+func TestFollowPodLogs(t *testing.T) {
+	pending := newRunningPod("testconnector-c", "testconnector-connector", "app")
+	pending.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+	toggler, clientset := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 2),
+		newRunningPod("testconnector-a", "testconnector-connector", "app", "sidecar"),
+		pending,
+	)
+	out := followFor(t, toggler, 150*time.Millisecond, func() {
+		clientset.CoreV1().Pods(namespace).Create(context.Background(), newRunningPod("testconnector-b", "testconnector-connector", "app"), metav1.CreateOptions{})
+	})
+	exLines := []string{"[testconnector-a/app] fake logs", "[testconnector-a/sidecar] fake logs", "[testconnector-b/app] fake logs"}
+	if lines := out.sortedLines(); strings.Join(lines, "\n") != strings.Join(exLines, "\n") {
+		t.Errorf("Followed incorrect logs, got: %q, want: %q", lines, exLines)
+	}
+}
+
+//Tests following a container that restarts. Should stream its new log again
+func TestFollowPodLogs_Restart(t *testing.T) {
+	toggler, clientset := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newRunningPod("testconnector-a", "testconnector-connector", "app"),
+	)
+	out := followFor(t, toggler, 150*time.Millisecond, func() {
+		restarted := newRunningPod("testconnector-a", "testconnector-connector", "app")
+		restarted.Status.ContainerStatuses[0].RestartCount = 1
+		clientset.CoreV1().Pods(namespace).Update(context.Background(), restarted, metav1.UpdateOptions{})
+	})
+	exLines := []string{"[testconnector-a/app] fake logs", "[testconnector-a/app] fake logs"}
+	if lines := out.sortedLines(); strings.Join(lines, "\n") != strings.Join(exLines, "\n") {
+		t.Errorf("Followed incorrect logs, got: %q, want: %q", lines, exLines)
+	}
+}
+
+//Tests following a container whose log is forbidden. Should print the error once instead of asking again on every poll
+func TestFollowPodLogs_Forbidden(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newDeployment("testconnector-connector", namespace, nil, 1), newRunningPod("testconnector-a", "testconnector-connector", "app"))
+	requests := int32(0)
+	toggler := NewToggler(failingLogsClientset{clientset, http.StatusForbidden, &requests})
+	out := followFor(t, toggler, 100*time.Millisecond, func() {})
+	lines := out.sortedLines()
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "[testconnector-a/app] error: ") || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Followed incorrect logs, got: %q after %d requests, want: one error after 1 request", lines, requests)
+	}
+}
+
+//Tests following a container that is still starting, which the API server answers with Bad Request. Should ask again on the next poll
+func TestFollowPodLogs_BadRequest(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newDeployment("testconnector-connector", namespace, nil, 1), newRunningPod("testconnector-a", "testconnector-connector", "app"))
+	requests := int32(0)
+	toggler := NewToggler(failingLogsClientset{clientset, http.StatusBadRequest, &requests})
+	out := followFor(t, toggler, 100*time.Millisecond, func() {})
+	if atomic.LoadInt32(&requests) < 2 || out.buf.String() != "" {
+		t.Errorf("Followed incorrect logs, got: %q after %d requests, want: none after several requests", out.buf.String(), requests)
+	}
+}
+
+//Tests following a deployment that does not exist. Should return an error
+func TestFollowPodLogs_NonExistentDeployment(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	if err := toggler.FollowPodLogs(context.Background(), "missing-connector", namespace, allLogs); err == nil {
+		t.Errorf("Expected error for missing deployment")
+	}
+}
+
+//Tests following only the container named with --container. Should stream no other container
+func TestFollowPodLogs_Container(t *testing.T) {
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newRunningPod("testconnector-a", "testconnector-connector", "app", "sidecar"),
+	)
+	out := &syncBuffer{}
+	toggler.out = out
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := toggler.FollowPodLogs(ctx, "testconnector-connector", namespace, logOptions{tail: -1, container: "sidecar"})
+	exLines := []string{"[testconnector-a/sidecar] fake logs"}
+	if lines := out.sortedLines(); err != nil || strings.Join(lines, "\n") != strings.Join(exLines, "\n") {
+		t.Errorf("Followed incorrect logs, got: %q, want: %q, error: %v", lines, exLines, err)
+	}
+}
+
+/*
+	Unit test logOptions
+*/
+
+//Tests mapping every log flag to corev1.PodLogOptions. Should round --since up to whole seconds
+func TestPodLogOptions(t *testing.T) {
+	sinceTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	opts := logOptions{since: 1500 * time.Millisecond, tail: 20, timestamps: true, previous: true}
+	out := opts.podLogOptions("app", false)
+	if out.Container != "app" || *out.SinceSeconds != 2 || *out.TailLines != 20 || !out.Timestamps || !out.Previous || out.Follow {
+		t.Errorf("Returned incorrect options for %+v, got: %+v", opts, out)
+	}
+
+	opts = logOptions{sinceTime: sinceTime, tail: -1}
+	out = opts.podLogOptions("", true)
+	if !out.SinceTime.Time.Equal(sinceTime) || out.SinceSeconds != nil || out.TailLines != nil || !out.Follow {
+		t.Errorf("Returned incorrect options for %+v, got: %+v", opts, out)
+	}
+}
+
+//Tests choosing the containers of a pod with init containers and a sidecar, one of which restarted
+func TestLogOptions_Containers(t *testing.T) {
+	pod := newRunningPod("testconnector-a", "testconnector-connector", "app", "sidecar")
+	pod.Spec.InitContainers = []corev1.Container{{Name: "migrate"}}
+	pod.Status.ContainerStatuses[0].RestartCount = 2
+	cases := []struct {
+		opts  logOptions
+		exOut []string
+	}{
+		{allLogs, []string{"app", "sidecar"}},
+		{logOptions{tail: -1, allContainers: true}, []string{"migrate", "app", "sidecar"}},
+		{logOptions{tail: -1, container: "sidecar"}, []string{"sidecar"}},
+		{logOptions{tail: -1, previous: true}, []string{"app"}},
+		{logOptions{tail: -1, previous: true, container: "sidecar"}, []string{"sidecar"}},
+	}
+	for _, c := range cases {
+		if out := c.opts.containers(*pod); strings.Join(out, ",") != strings.Join(c.exOut, ",") {
+			t.Errorf("Returned incorrect containers for %+v, got: %v, want: %v", c.opts, out, c.exOut)
+		}
+	}
+
+	//A pod without containers in its spec leaves the choice to the API server
+	if out := allLogs.containers(*newPod("testconnector-b", namespace, "testconnector-connector", time.Now())); len(out) != 1 || out[0] != "" {
+		t.Errorf("Returned incorrect containers, got: %q, want: [\"\"]", out)
+	}
+}
+
+//Tests log flags that contradict each other. Should return an error for each
+func TestCheckLogOptions(t *testing.T) {
+	cases := []struct {
+		opts   logOptions
+		follow bool
+	}{
+		{logOptions{tail: -1, since: -time.Minute}, false},
+		{logOptions{tail: -1, since: time.Minute, sinceTime: time.Now()}, false},
+		{logOptions{tail: -2}, false},
+		{logOptions{tail: -1, container: "app", allContainers: true}, false},
+		{logOptions{tail: -1, previous: true}, true},
+	}
+	for _, c := range cases {
+		if err := checkLogOptions(c.opts, c.follow); err == nil {
+			t.Errorf("Expected error for %+v with follow %v", c.opts, c.follow)
+		}
+	}
+	if err := checkLogOptions(logOptions{tail: 10, since: time.Minute, container: "app", previous: true}, false); err != nil {
+		t.Errorf("Returned unexpected error: %v", err)
+	}
+}
+
+/*
+	Integration test podLogResults
+*/
+
+//Tests reading the logs of a pod with a sidecar. Should return one result per container, labelled "pod/container"
+func TestPodLogResults_Containers(t *testing.T) {
+	toggler, _ := newFakeToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newRunningPod("testconnector-a", "testconnector-connector", "app", "sidecar"),
+	)
+	results, err := toggler.podLogResults("testconnector-connector", namespace, allLogs)
+	out := new(bytes.Buffer)
+	results.text(out, false)
+	exOut := "testconnector-a/app: fake logs\ntestconnector-a/sidecar: fake logs\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Returned incorrect logs, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+/*
+	Unit test isTerminal
+*/
+
+//Tests a buffer and a regular file. Should not be treated as terminals, so no colors are written
+func TestIsTerminal(t *testing.T) {
+	if isTerminal(new(bytes.Buffer)) {
+		t.Errorf("Expected a buffer not to be a terminal")
+	}
+	f, err := os.Create(filepath.Join(t.TempDir(), "logs"))
+	if err != nil || isTerminal(f) {
+		t.Errorf("Expected a file not to be a terminal, error: %v", err)
+	}
+}
+
+//Tests the --follow flag. Should set follow on the kubeCmd
+func TestParseArgs_Follow(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getPodLogs", "-f", "myConnector", "myNamespace"}
+	if args := parseArgs(osArgs); !args.follow || args.names[0] != "myConnector" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
+
+//Tests the log flags. Should map them onto the kubeCmd's logOptions
+func TestParseArgs_LogOptions(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getPodLogs", "--since-time", "2021-03-01T12:00:00Z", "--tail", "50", "--timestamps", "-p", "-c", "app", "myConnector", "myNamespace"}
+	exLogs := logOptions{sinceTime: time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), tail: 50, timestamps: true, previous: true, container: "app"}
+	if args := parseArgs(osArgs); !args.logs.sinceTime.Equal(exLogs.sinceTime) || args.logs.tail != 50 || !args.logs.timestamps ||
+		!args.logs.previous || args.logs.container != "app" || args.names[0] != "myConnector" {
+		t.Errorf("Returned incorrect logOptions for %v, got: %+v, want: %+v", osArgs, args.logs, exLogs)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* logSource is one container log being merged, read a line at a time from its stream. last is the time of the latest line read */
+type logSource struct {
+	pod       string
+	container string
+	prefix    string
+	stream    io.Closer
+	reader    *bufio.Reader
+	filter    *contextFilter
+	last      time.Time
+	done      bool
+}
+
+/* name returns the "pod/container" the log belongs to */
+func (s *logSource) name() string {
+	return podLogResult{Pod: s.pod, Container: s.container}.source()
+}
+
+/* mergedLine is the next line of one of the logs being merged, with the time its container wrote it. A line that passed a filter
+   comes with the context lines printed before it */
+type mergedLine struct {
+	time   time.Time
+	lines  []string
+	source int
+}
+
+/* mergeHeap holds the next line of every log being merged, earliest first. Lines written at the same time are ordered by their
+   source, so that the lines of a single log never change places */
+type mergeHeap []mergedLine
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if !h[i].time.Equal(h[j].time) {
+		return h[i].time.Before(h[j].time)
+	}
+	return h[i].source < h[j].source
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergedLine)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	line := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return line
+}
+
+/* splitTimestamp splits a log line read with PodLogOptions.Timestamps into the RFC3339 time the API server put in front of it and
+   the line itself. ok is false if the line doesn't start with a timestamp */
+func splitTimestamp(line string) (time.Time, string, bool) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return time.Time{}, line, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, line[:i])
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return ts, line[i+1:], true
+}
+
+/* mergeLogs writes the lines of every source that pass its filter to out in the order they were written, each prefixed with its
+   source's prefix, and with its timestamp unless timestamps is false */
+func mergeLogs(out io.Writer, sources []*logSource, timestamps bool) error {
+
+	//Only the next line of each source is held, so logs of any size are merged in memory proportional to the number of sources
+	h := &mergeHeap{}
+	errs := []error{}
+
+	//next reads the next line of the i'th source that passes its filter onto the heap, unless the source is done
+	next := func(i int) {
+		s := sources[i]
+		for !s.done {
+			line, err := s.reader.ReadString('\n')
+
+			//A log that fails part way through is merged up to the failure
+			if err != nil {
+				s.done = true
+				if err != io.EOF {
+					errs = append(errs, fmt.Errorf("error: reading the log of %s: %v", s.name(), err))
+				}
+			}
+			if line == "" {
+				continue
+			}
+			line = strings.TrimSuffix(line, "\n")
+
+			//A line without a timestamp keeps the time of the line before it, so it stays right after it
+			if ts, text, ok := splitTimestamp(line); ok {
+				s.last = ts
+				if !timestamps {
+					line = text
+				}
+			}
+			if lines := s.filter.next(line); len(lines) > 0 {
+				heap.Push(h, mergedLine{time: s.last, lines: lines, source: i})
+				return
+			}
+		}
+	}
+
+	for i := range sources {
+		next(i)
+	}
+	for h.Len() > 0 {
+		line := heap.Pop(h).(mergedLine)
+		for _, l := range line.lines {
+			if _, err := fmt.Fprintf(out, "%s%s\n", sources[line.source].prefix, l); err != nil {
+				return err
+			}
+		}
+		next(line.source)
+	}
+	return joinErrors(errs...)
+}
+
+/* MergePodLogs writes the logs of the containers chosen by opts of every pod of the given deployment to the Toggler's output as a
+   single stream in the order the lines were written, each prefixed with "[pod/container] " */
+func (t *Toggler) MergePodLogs(deploymentName string, namespace string, opts logOptions) error {
+	pods, err := t.getPods(deploymentName, namespace)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	//The logs are always requested with timestamps to order them, which mergeLogs strips again unless opts asks for them
+	requested := opts
+	requested.timestamps = true
+
+	//Opens the logs of the pods in parallel, keeping them in pod order. A log that can't be opened is left out of the merge
+	found := make([][]*logSource, len(pods))
+	openErr := t.forEach(len(pods), func(worker *Toggler, i int) error {
+		errs := []error{}
+		for _, c := range opts.containers(pods[i]) {
+			stream, err := worker.clientset.CoreV1().Pods(namespace).GetLogs(pods[i].Name, requested.podLogOptions(c, false)).Stream(context.Background())
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			found[i] = append(found[i], &logSource{
+				pod:       pods[i].Name,
+				container: c,
+				stream:    stream,
+				reader:    bufio.NewReader(stream),
+				filter:    opts.filter.newContext(),
+			})
+		}
+		return joinErrors(errs...)
+	})
+	defer func() {
+		for _, podSources := range found {
+			for _, s := range podSources {
+				s.stream.Close()
+			}
+		}
+	}()
+
+	prefixes := newLogPrefixes(t.out)
+	sources := []*logSource{}
+	for _, podSources := range found {
+		for _, s := range podSources {
+			s.prefix = prefixes.prefix(s.pod, s.container)
+			sources = append(sources, s)
+		}
+	}
+	return joinErrors(openErr, mergeLogs(t.out, sources, opts.timestamps))
+}
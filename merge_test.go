@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* newLogSource returns a logSource for the given pod that reads the given log */
+func newLogSource(pod string, log io.Reader) *logSource {
+	return &logSource{pod: pod, prefix: "[" + pod + "] ", stream: ioutil.NopCloser(log), reader: bufio.NewReader(log)}
+}
+
+/* failingReader returns its log and then fails with err instead of io.EOF */
+type failingReader struct {
+	log io.Reader
+	err error
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.log.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+/*
+	Unit test splitTimestamp
+*/
+
+//Tests lines with and without the timestamp the API server adds. Should only split the former
+func TestSplitTimestamp(t *testing.T) {
+	ts, text, ok := splitTimestamp("2021-03-01T12:00:00.123456789Z GET /orders 200")
+	exTs := time.Date(2021, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	if !ok || !ts.Equal(exTs) || text != "GET /orders 200" {
+		t.Errorf("Split incorrectly, got: %v %q %v, want: %v %q true", ts, text, ok, exTs, "GET /orders 200")
+	}
+	for _, line := range []string{"GET /orders 200", "panic:", ""} {
+		if _, text, ok := splitTimestamp(line); ok || text != line {
+			t.Errorf("Split a line without a timestamp, got: %q %v, want: %q false", text, ok, line)
+		}
+	}
+}
+
+/*
+	Unit test mergeLogs
+*/
+
+//Tests merging two logs. Should interleave their lines by time and strip the timestamps
+func TestMergeLogs(t *testing.T) {
+	sources := []*logSource{
+		newLogSource("web-a", strings.NewReader("2021-03-01T12:00:01Z a1\n2021-03-01T12:00:03Z a2\n2021-03-01T12:00:04Z a3\n")),
+		newLogSource("web-b", strings.NewReader("2021-03-01T12:00:02Z b1\n2021-03-01T12:00:03Z b2\n2021-03-01T12:00:05Z b3")),
+	}
+	out := new(bytes.Buffer)
+	err := mergeLogs(out, sources, false)
+	exOut := "[web-a] a1\n[web-b] b1\n[web-a] a2\n[web-b] b2\n[web-a] a3\n[web-b] b3\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Merged incorrectly, got:\n%v\nwant:\n%v\nerror: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests merging a log with a line that has no timestamp, and keeping the timestamps. Should keep the line after the one before it
+func TestMergeLogs_Timestamps(t *testing.T) {
+	sources := []*logSource{
+		newLogSource("web-a", strings.NewReader("2021-03-01T12:00:01Z panic: nil map\ngoroutine 1 [running]:\n2021-03-01T12:00:04Z a2\n")),
+		newLogSource("web-b", strings.NewReader("2021-03-01T12:00:02Z b1\n")),
+	}
+	out := new(bytes.Buffer)
+	err := mergeLogs(out, sources, true)
+	exOut := "[web-a] 2021-03-01T12:00:01Z panic: nil map\n[web-a] goroutine 1 [running]:\n[web-b] 2021-03-01T12:00:02Z b1\n[web-a] 2021-03-01T12:00:04Z a2\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Merged incorrectly, got:\n%v\nwant:\n%v\nerror: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests merging a log that fails part way through. Should merge the rest and return the error with the log's name
+func TestMergeLogs_ReadError(t *testing.T) {
+	sources := []*logSource{
+		newLogSource("web-a", failingReader{strings.NewReader("2021-03-01T12:00:03Z a1\n"), errors.New("connection reset")}),
+		newLogSource("web-b", strings.NewReader("2021-03-01T12:00:02Z b1\n")),
+	}
+	out := new(bytes.Buffer)
+	err := mergeLogs(out, sources, false)
+	exOut := "[web-b] b1\n[web-a] a1\n"
+	if err == nil || !strings.Contains(err.Error(), "web-a: connection reset") || out.String() != exOut {
+		t.Errorf("Merged incorrectly, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+/*
+	Integration test MergePodLogs
+*/
+
+//Tests merging the logs of a deployment against the fake clientset, whose logs have no timestamps. Should keep pod and container order
+func TestMergePodLogs(t *testing.T) {
+	toggler, _, out := newBufferedToggler(
+		newDeployment("testconnector-connector", namespace, nil, 2),
+		newRunningPod("testconnector-b", "testconnector-connector", "app"),
+		newRunningPod("testconnector-a", "testconnector-connector", "app", "sidecar"),
+	)
+	err := toggler.MergePodLogs("testconnector-connector", namespace, allLogs)
+	exOut := "[testconnector-a/app] fake logs\n[testconnector-a/sidecar] fake logs\n[testconnector-b/app] fake logs\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Merged incorrect logs, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests merging the logs of a deployment that does not exist. Should return an error
+func TestMergePodLogs_NonExistentDeployment(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	if err := toggler.MergePodLogs("missing-connector", namespace, allLogs); err == nil {
+		t.Errorf("Expected error for missing deployment")
+	}
+}
+
+//Tests the --merge flag. Should set merge on the kubeCmd
+func TestParseArgs_Merge(t *testing.T) {
+	osArgs := []string{"kubeToggler", "getPodLogs", "--merge", "myConnector", "myNamespace"}
+	if args := parseArgs(osArgs); !args.merge || args.names[0] != "myConnector" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+/* namespaceTargets holds the names of the workloads of one kind a command targets in a single namespace */
+type namespaceTargets struct {
+	namespace string
+	kind      string
+	names     []string
+
+	//The labels of each workload found with a label selector, keyed by name, so that getName needn't get them again. nil for names
+	labels map[string]map[string]string
+}
+
+/* ResolveNamespaces turns a NAMESPACE argument into the list of namespaces it stands for. The argument can be a single namespace or a
+   comma-separated list of them. An empty argument (--all-namespaces) stands for every namespace in the cluster, and a non-nil
+   namespaceSelector for every namespace whose labels match it */
+func (t *Toggler) ResolveNamespaces(namespaceArg string, namespaceSelector labels.Selector) ([]string, error) {
+	if namespaceArg == metav1.NamespaceAll || namespaceSelector != nil {
+		if namespaceSelector == nil {
+			namespaceSelector = labels.Everything()
+		}
+		namespaceList, err := t.clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{LabelSelector: namespaceSelector.String()})
+		if err != nil {
+			return nil, err
+		}
+		namespaces := []string{}
+		for _, ns := range namespaceList.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+		if len(namespaces) == 0 {
+			return nil, fmt.Errorf("error: no namespaces match %q", namespaceSelector.String())
+		}
+		return namespaces, nil
+	}
+
+	namespaces := []string{}
+	for _, ns := range strings.Split(namespaceArg, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces, nil
+}
+
+/* getTargets finds the workloads of the given kinds targeted by the label selector or names in each of the given namespaces, grouped by
+   namespace and then kind. With several kinds each name is looked up to find out which kind it is */
+func (t *Toggler) getTargets(kinds []string, selector labels.Selector, names []string, namespaces []string) ([]namespaceTargets, error) {
+	if names == nil && selector == nil {
+		return nil, errors.New("error: there must be at least one targeting field (either names or labels)")
+	}
+
+	targets := []namespaceTargets{}
+	for _, ns := range namespaces {
+		for _, kind := range kinds {
+			target := namespaceTargets{namespace: ns, kind: kind}
+			switch {
+			case names != nil && len(kinds) == 1:
+				target.names = names
+			case names != nil:
+				for _, n := range names {
+					_, err := t.getWorkload(kind, n, ns)
+					if err == nil {
+						target.names = append(target.names, n)
+					} else if !apierrors.IsNotFound(err) {
+						return nil, err
+					}
+				}
+			default:
+				workloads, err := t.listWorkloads(kind, selector, ns)
+				if err != nil {
+					return nil, err
+				}
+				target.labels = map[string]map[string]string{}
+				for _, w := range workloads {
+					target.names = append(target.names, w.name)
+					target.labels[w.name] = w.labels
+				}
+			}
+			if len(target.names) > 0 {
+				targets = append(targets, target)
+			}
+		}
+
+		//A name that is none of the kinds is still targeted as the first kind, like a single --kind would, so that the command
+		//reports it as failed without giving up on the other names
+		if names != nil && len(kinds) > 1 {
+			unknown := []string{}
+			for _, n := range names {
+				if !targetsName(targets, ns, n) {
+					unknown = append(unknown, n)
+				}
+			}
+			if len(unknown) > 0 {
+				targets = addTargetNames(targets, ns, kinds[0], unknown)
+			}
+		}
+	}
+
+	//With a selector, namespaces without a matching workload are left out and only no match at all is an error
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("error: %s does not exist", kindsDescription(kinds))
+	}
+	return targets, nil
+}
+
+/* addTargetNames adds names to the targets of the given kind in the given namespace, or adds those targets if there are none yet */
+func addTargetNames(targets []namespaceTargets, namespace string, kind string, names []string) []namespaceTargets {
+	for i := range targets {
+		if targets[i].namespace == namespace && targets[i].kind == kind {
+			targets[i].names = append(targets[i].names, names...)
+			return targets
+		}
+	}
+	return append(targets, namespaceTargets{namespace: namespace, kind: kind, names: names})
+}
+
+/* targetsName returns true if any of the targets in the given namespace contains the given name */
+func targetsName(targets []namespaceTargets, namespace string, name string) bool {
+	for _, target := range targets {
+		if target.namespace != namespace {
+			continue
+		}
+		for _, n := range target.names {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* commandTargets resolves the namespaces of a kubeCmd and finds the workloads it targets in each of them */
+func (t *Toggler) commandTargets(args kubeCmd) ([]namespaceTargets, error) {
+	namespaces, err := t.ResolveNamespaces(args.namespace, args.namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	return t.getTargets(args.kinds, args.selector, args.names, namespaces)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/* newNamespace returns a namespace fixture with the given labels */
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+/* multiNamespaceObjects returns three namespaces where only dev-1 and dev-3 run a deployment labelled feature=payments */
+func multiNamespaceObjects() []runtime.Object {
+	return []runtime.Object{
+		newNamespace("dev-1", map[string]string{"team": "payments"}),
+		newNamespace("dev-2", map[string]string{"team": "payments"}),
+		newNamespace("dev-3", map[string]string{"team": "search"}),
+		newDeployment("payments-api", "dev-1", map[string]string{"feature": "payments"}, 1),
+		newDeployment("payments-worker", "dev-1", map[string]string{"feature": "payments"}, 1),
+		newDeployment("search-api", "dev-2", map[string]string{"feature": "search"}, 1),
+		newDeployment("payments-api", "dev-3", map[string]string{"feature": "payments"}, 1),
+	}
+}
+
+/*
+	Integration test ResolveNamespaces
+*/
+
+//Tests a comma-separated namespace list. Should return each namespace without asking the cluster
+func TestResolveNamespaces_List(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	exOut := []string{"dev-1", "dev-4"}
+	out, err := toggler.ResolveNamespaces("dev-1, dev-4", nil)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect namespaces, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests an empty namespace argument. Should return every namespace in the cluster
+func TestResolveNamespaces_All(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	exOut := []string{"dev-1", "dev-2", "dev-3"}
+	out, err := toggler.ResolveNamespaces("", nil)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect namespaces, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests a namespace selector. Should return only the namespaces labelled team=payments
+func TestResolveNamespaces_Selector(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	exOut := []string{"dev-1", "dev-2"}
+	out, err := toggler.ResolveNamespaces("", labels.SelectorFromSet(map[string]string{"team": "payments"}))
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect namespaces, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests a namespace selector that matches nothing. Should return an error
+func TestResolveNamespaces_NoMatch(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	out, err := toggler.ResolveNamespaces("", labels.SelectorFromSet(map[string]string{"team": "none"}))
+	if err == nil {
+		t.Errorf("Expected error for selector without matches, got: %v, error: %v", out, err)
+	}
+}
+
+/*
+	Integration test getTargets
+*/
+
+//Tests a label selector across namespaces. Should leave out dev-2, which has no matching deployment
+func TestGetTargets_Labels(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	payments := map[string]string{"feature": "payments"}
+	exOut := []namespaceTargets{
+		{namespace: "dev-1", kind: kindDeployment, names: []string{"payments-api", "payments-worker"}, labels: map[string]map[string]string{"payments-api": payments, "payments-worker": payments}},
+		{namespace: "dev-3", kind: kindDeployment, names: []string{"payments-api"}, labels: map[string]map[string]string{"payments-api": payments}},
+	}
+	out, err := toggler.getTargets([]string{kindDeployment}, labels.SelectorFromSet(map[string]string{"feature": "payments"}), nil, []string{"dev-1", "dev-2", "dev-3"})
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect targets, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests names across namespaces. Should target the names in every namespace
+func TestGetTargets_Names(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	exOut := []namespaceTargets{
+		{namespace: "dev-1", kind: kindDeployment, names: []string{"payments-api"}},
+		{namespace: "dev-3", kind: kindDeployment, names: []string{"payments-api"}},
+	}
+	out, err := toggler.getTargets([]string{kindDeployment}, nil, []string{"payments-api"}, []string{"dev-1", "dev-3"})
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect targets, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests a label selector without matches in any namespace. Should return an error
+func TestGetTargets_NoMatch(t *testing.T) {
+	toggler, _ := newFakeToggler(multiNamespaceObjects()...)
+	out, err := toggler.getTargets([]string{kindDeployment}, labels.SelectorFromSet(map[string]string{"feature": "none"}), nil, []string{"dev-1", "dev-2"})
+	if err == nil {
+		t.Errorf("Expected error for selector without matches, got: %+v, error: %v", out, err)
+	}
+}
+
+/*
+	Integration test nameResults
+*/
+
+//Tests getName on a label selector. Should take the labels from the list without getting each deployment again
+func TestNameResults_Labels(t *testing.T) {
+	toggler, clientset := newFakeToggler(multiNamespaceObjects()...)
+	targets, err := toggler.getTargets([]string{kindDeployment}, labels.SelectorFromSet(map[string]string{"feature": "payments"}), nil, []string{"dev-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientset.ClearActions()
+	out, err := toggler.nameResults(targets[0])
+	exOut := nameResults{
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "payments-api", Labels: map[string]string{"feature": "payments"}},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "payments-worker", Labels: map[string]string{"feature": "payments"}},
+	}
+	if err != nil || !reflect.DeepEqual(out, exOut) || len(clientset.Actions()) != 0 {
+		t.Errorf("Returned incorrect names, got: %+v, want: %+v, requests: %v, error: %v", out, exOut, clientset.Actions(), err)
+	}
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+/* The --output formats every read command can print its results in. Without --output the results are printed in kubeToggler's
+   original "name: value" text format */
+const (
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputTable = "table"
+	outputName  = "name"
+)
+
+/* The --output formats that take a template after an equals sign, e.g. -o jsonpath='{.items[*].name}'. Like kubectl, a go-template
+   is executed against the result structs and so uses their Go field names (.Items, .Name, .Replicas), while a jsonpath uses the
+   field names of the -o json document (.items, .name, .replicas) */
+const (
+	outputGoTemplate = "go-template"
+	outputJSONPath   = "jsonpath"
+)
+
+/* templateFormat splits a go-template=TEMPLATE or jsonpath=TEMPLATE --output value into the format and the template. It returns false
+   for every other --output value */
+func templateFormat(format string) (string, string, bool) {
+	for _, prefix := range []string{outputGoTemplate, outputJSONPath} {
+		if strings.HasPrefix(format, prefix+"=") {
+			return prefix, strings.TrimPrefix(format, prefix+"="), true
+		}
+	}
+	return "", "", false
+}
+
+/* checkOutputFormat returns an error if kubeToggler can't print results in the given --output format, including a go-template or
+   jsonpath that does not parse */
+func checkOutputFormat(format string) error {
+	if kind, text, ok := templateFormat(format); ok {
+		_, err := parseTemplate(kind, text)
+		return err
+	}
+	switch format {
+	case "", outputJSON, outputYAML, outputTable, outputName:
+		return nil
+	}
+	return fmt.Errorf("error: unknown output format %q, must be json, yaml, table, name, go-template=TEMPLATE or jsonpath=TEMPLATE", format)
+}
+
+/* resultTemplate is a parsed go-template or jsonpath, both of which write their output for a given object */
+type resultTemplate interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+/* parseTemplate parses a go-template or jsonpath template. A jsonpath without braces, such as .items[*].name, is wrapped in them the
+   way kubectl does. Keys missing from a result are printed as empty rather than failing the command */
+func parseTemplate(kind string, text string) (resultTemplate, error) {
+	if kind == outputGoTemplate {
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid go-template: %v", err)
+		}
+		return tmpl, nil
+	}
+	if !strings.Contains(text, "{") {
+		text = "{" + text + "}"
+	}
+	jp := jsonpath.New("output").AllowMissingKeys(true)
+	if err := jp.Parse(text); err != nil {
+		return nil, fmt.Errorf("error: invalid jsonpath: %v", err)
+	}
+	return jp, nil
+}
+
+/* resultSet is implemented by the results of every read command so that printResults can print them in any --output format */
+type resultSet interface {
+
+	//listKind is the "kind" of the JSON and YAML document, e.g. ScaleList
+	listKind() string
+
+	//text writes the results in the format kubeToggler printed before --output existed
+	text(w io.Writer, multiNamespace bool)
+
+	//table returns the header and rows of the table format. The NAMESPACE column is only included for multi-namespace commands
+	table(multiNamespace bool) ([]string, [][]string)
+
+	//sortBy sorts the results by namespace then name, and then by the given --sort-by field if there is one
+	sortBy(field string) error
+}
+
+/* namedResultSet is a resultSet whose results each have a name, which is all -o name prints */
+type namedResultSet interface {
+	resultSet
+	names() []string
+}
+
+/* resultList is the top level document of -o json and -o yaml. Its items are the command's result structs */
+type resultList struct {
+	Kind  string    `json:"kind"`
+	Items resultSet `json:"items"`
+}
+
+/* printResults writes the results of a read command to w in the given --output format */
+func printResults(w io.Writer, format string, results resultSet, multiNamespace bool) error {
+	if kind, text, ok := templateFormat(format); ok {
+		tmpl, err := parseTemplate(kind, text)
+		if err != nil {
+			return err
+		}
+		if kind == outputGoTemplate {
+			return tmpl.Execute(w, resultList{Kind: results.listKind(), Items: results})
+		}
+
+		//jsonpath runs against the -o json document rather than the structs, the same way kubectl runs it against unstructured objects.
+		//utiljson decodes whole numbers as int64 so that filters like ?(@.replicas==0) can compare them
+		doc, err := json.Marshal(resultList{Kind: results.listKind(), Items: results})
+		if err != nil {
+			return err
+		}
+		data := map[string]interface{}{}
+		if err := utiljson.Unmarshal(doc, &data); err != nil {
+			return err
+		}
+		return tmpl.Execute(w, data)
+	}
+
+	switch format {
+	case "":
+		results.text(w, multiNamespace)
+	case outputJSON:
+		out, err := json.MarshalIndent(resultList{Kind: results.listKind(), Items: results}, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(out))
+	case outputYAML:
+		out, err := yaml.Marshal(resultList{Kind: results.listKind(), Items: results})
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(out))
+	case outputTable:
+		header, rows := results.table(multiNamespace)
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case outputName:
+		named, ok := results.(namedResultSet)
+		if !ok {
+			return fmt.Errorf("error: output format %q is not supported for %s", format, results.listKind())
+		}
+		for _, name := range named.names() {
+			fmt.Fprintln(w, name)
+		}
+	default:
+		return checkOutputFormat(format)
+	}
+	return nil
+}
+
+/* sortResults sorts a slice of results with 'byName', which orders them by namespace then name, and then, if a --sort-by field is
+   given, stable sorts them again by namespace and that field so that grouped output stays grouped. Fields may have a leading dot */
+func sortResults(results interface{}, namespaceOf func(i int) string, byName func(i, j int) bool, fields map[string]func(i, j int) bool, field string) error {
+	sort.SliceStable(results, byName)
+	if field == "" {
+		return nil
+	}
+	less, ok := fields[strings.ToLower(strings.TrimPrefix(field, "."))]
+	if !ok {
+		valid := []string{}
+		for f := range fields {
+			valid = append(valid, f)
+		}
+		sort.Strings(valid)
+		return fmt.Errorf("error: cannot sort by %q, must be one of %s", field, strings.Join(valid, ", "))
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if namespaceOf != nil && namespaceOf(i) != namespaceOf(j) {
+			return namespaceOf(i) < namespaceOf(j)
+		}
+		return less(i, j)
+	})
+	return nil
+}
+
+/* withNamespace prepends the namespace column to a table row (or header) when the command spans several namespaces */
+func withNamespace(multiNamespace bool, namespace string, row ...string) []string {
+	if !multiNamespace {
+		return row
+	}
+	return append([]string{namespace}, row...)
+}
+
+/* namespaceHeader returns true if result i is the first of its namespace, which is where the text format prints a namespace header */
+func namespaceHeader(namespaces []string, i int) bool {
+	return i == 0 || namespaces[i-1] != namespaces[i]
+}
+
+/* scaleResult is the result getScale prints for each workload. For a DaemonSet, replicas is the number of nodes that should run its
+   pod and currentReplicas the number that do */
+type scaleResult struct {
+	Namespace       string            `json:"namespace"`
+	Kind            string            `json:"kind"`
+	Name            string            `json:"name"`
+	Replicas        int32             `json:"replicas"`
+	CurrentReplicas int32             `json:"currentReplicas"`
+	ReadyReplicas   int32             `json:"readyReplicas"`
+	Labels          map[string]string `json:"labels,omitempty"`
+}
+
+/* scale returns the scale getScale prints for the workload, which is "desired/current" for a DaemonSet */
+func (r scaleResult) scale() string {
+	if r.Kind == kindDaemonSet {
+		return fmt.Sprintf("%d/%d", r.Replicas, r.CurrentReplicas)
+	}
+	return strconv.Itoa(int(r.Replicas))
+}
+
+type scaleResults []scaleResult
+
+func (results scaleResults) listKind() string { return "ScaleList" }
+
+func (results scaleResults) text(w io.Writer, multiNamespace bool) {
+	namespaces := []string{}
+	for _, r := range results {
+		namespaces = append(namespaces, r.Namespace)
+	}
+	for i, r := range results {
+		if !multiNamespace {
+			fmt.Fprintf(w, "%s: %s\n", displayName(r.Kind, r.Name), r.scale())
+			continue
+		}
+		if namespaceHeader(namespaces, i) {
+			fmt.Fprintf(w, "%s:\n", r.Namespace)
+		}
+		fmt.Fprintf(w, "  %s: %s\n", displayName(r.Kind, r.Name), r.scale())
+	}
+}
+
+func (results scaleResults) table(multiNamespace bool) ([]string, [][]string) {
+	rows := [][]string{}
+	for _, r := range results {
+		rows = append(rows, withNamespace(multiNamespace, r.Namespace, displayName(r.Kind, r.Name), r.scale()))
+	}
+	return withNamespace(multiNamespace, "NAMESPACE", "NAME", "SCALE"), rows
+}
+
+func (results scaleResults) sortBy(field string) error {
+	byName := func(i, j int) bool {
+		if results[i].Namespace != results[j].Namespace {
+			return results[i].Namespace < results[j].Namespace
+		}
+		return displayName(results[i].Kind, results[i].Name) < displayName(results[j].Kind, results[j].Name)
+	}
+	return sortResults(results, func(i int) string { return results[i].Namespace }, byName, map[string]func(i, j int) bool{
+		"name":      byName,
+		"namespace": func(i, j int) bool { return results[i].Namespace < results[j].Namespace },
+		"kind":      func(i, j int) bool { return results[i].Kind < results[j].Kind },
+		"scale":     func(i, j int) bool { return results[i].Replicas < results[j].Replicas },
+		"replicas":  func(i, j int) bool { return results[i].Replicas < results[j].Replicas },
+		"ready":     func(i, j int) bool { return results[i].ReadyReplicas < results[j].ReadyReplicas },
+	}, field)
+}
+
+func (results scaleResults) names() []string {
+	names := []string{}
+	for _, r := range results {
+		names = append(names, displayName(r.Kind, r.Name))
+	}
+	return names
+}
+
+/* nameResult is the result getName prints for each deployment */
+type nameResult struct {
+	Namespace string            `json:"namespace"`
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type nameResults []nameResult
+
+func (results nameResults) listKind() string { return "NameList" }
+
+/* text prints the names space-separated, on one "namespace: names" line per namespace for multi-namespace commands */
+func (results nameResults) text(w io.Writer, multiNamespace bool) {
+	namespaces := []string{}
+	for _, r := range results {
+		namespaces = append(namespaces, r.Namespace)
+	}
+	for i, r := range results {
+		if multiNamespace && namespaceHeader(namespaces, i) {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s: ", r.Namespace)
+		}
+		fmt.Fprint(w, displayName(r.Kind, r.Name)+" ")
+	}
+	if multiNamespace && len(results) > 0 {
+		fmt.Fprintln(w)
+	}
+}
+
+func (results nameResults) table(multiNamespace bool) ([]string, [][]string) {
+	rows := [][]string{}
+	for _, r := range results {
+		rows = append(rows, withNamespace(multiNamespace, r.Namespace, displayName(r.Kind, r.Name)))
+	}
+	return withNamespace(multiNamespace, "NAMESPACE", "NAME"), rows
+}
+
+func (results nameResults) sortBy(field string) error {
+	byName := func(i, j int) bool {
+		if results[i].Namespace != results[j].Namespace {
+			return results[i].Namespace < results[j].Namespace
+		}
+		return results[i].Name < results[j].Name
+	}
+	return sortResults(results, func(i int) string { return results[i].Namespace }, byName, map[string]func(i, j int) bool{
+		"name":      byName,
+		"namespace": func(i, j int) bool { return results[i].Namespace < results[j].Namespace },
+	}, field)
+}
+
+func (results nameResults) names() []string {
+	names := []string{}
+	for _, r := range results {
+		names = append(names, displayName(r.Kind, r.Name))
+	}
+	return names
+}
+
+/* countResult is the result getNumWithLabels prints for each namespace */
+type countResult struct {
+	Namespace string `json:"namespace"`
+	Count     int    `json:"count"`
+}
+
+type countResults []countResult
+
+func (results countResults) listKind() string { return "CountList" }
+
+func (results countResults) text(w io.Writer, multiNamespace bool) {
+	for _, r := range results {
+		if multiNamespace {
+			fmt.Fprintf(w, "%s: %d\n", r.Namespace, r.Count)
+		} else {
+			fmt.Fprintln(w, r.Count)
+		}
+	}
+}
+
+func (results countResults) table(multiNamespace bool) ([]string, [][]string) {
+	rows := [][]string{}
+	for _, r := range results {
+		rows = append(rows, withNamespace(multiNamespace, r.Namespace, strconv.Itoa(r.Count)))
+	}
+	return withNamespace(multiNamespace, "NAMESPACE", "COUNT"), rows
+}
+
+func (results countResults) sortBy(field string) error {
+	byName := func(i, j int) bool { return results[i].Namespace < results[j].Namespace }
+	//Every count is a namespace of its own, so --sort-by count orders the namespaces themselves
+	return sortResults(results, nil, byName, map[string]func(i, j int) bool{
+		"namespace": byName,
+		"count":     func(i, j int) bool { return results[i].Count < results[j].Count },
+	}, field)
+}
+
+/* podLifetimeResult is the result getPodLifetimes prints for each pod of the deployment. Restarts is the sum of the restart counts of
+   the pod's containers */
+type podLifetimeResult struct {
+	Namespace string            `json:"namespace"`
+	Pod       string            `json:"pod"`
+	Created   time.Time         `json:"created"`
+	Lifetime  string            `json:"lifetime"`
+	Restarts  int32             `json:"restarts"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type podLifetimeResults []podLifetimeResult
+
+func (results podLifetimeResults) listKind() string { return "PodLifetimeList" }
+
+func (results podLifetimeResults) text(w io.Writer, multiNamespace bool) {
+	for _, r := range results {
+		fmt.Fprintf(w, "%s: %s\n", r.Pod, r.Lifetime)
+	}
+}
+
+func (results podLifetimeResults) table(multiNamespace bool) ([]string, [][]string) {
+	rows := [][]string{}
+	for _, r := range results {
+		rows = append(rows, []string{r.Pod, r.Created.Format(time.RFC3339), r.Lifetime, strconv.Itoa(int(r.Restarts))})
+	}
+	return []string{"POD", "CREATED", "LIFETIME", "RESTARTS"}, rows
+}
+
+/* sortBy sorts the pods by name, or by the --sort-by field. Sorting by age puts the oldest pod first, like kubectl's
+   --sort-by=.metadata.creationTimestamp */
+func (results podLifetimeResults) sortBy(field string) error {
+	byName := func(i, j int) bool { return results[i].Pod < results[j].Pod }
+	byAge := func(i, j int) bool { return results[i].Created.Before(results[j].Created) }
+	return sortResults(results, func(i int) string { return results[i].Namespace }, byName, map[string]func(i, j int) bool{
+		"name":     byName,
+		"pod":      byName,
+		"age":      byAge,
+		"created":  byAge,
+		"lifetime": byAge,
+		"restarts": func(i, j int) bool { return results[i].Restarts < results[j].Restarts },
+	}, field)
+}
+
+func (results podLifetimeResults) names() []string {
+	names := []string{}
+	for _, r := range results {
+		names = append(names, r.Pod)
+	}
+	return names
+}
+
+/* podLogResult is the result getPodLogs prints for each container of each pod of the deployment. Container is empty if the pod
+   spec didn't name its containers and the API server read the log of the pod's only one */
+type podLogResult struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	Log       string `json:"log"`
+}
+
+/* source returns the "pod/container" a log came from, or just the pod if the container is unnamed */
+func (r podLogResult) source() string {
+	if r.Container == "" {
+		return r.Pod
+	}
+	return r.Pod + "/" + r.Container
+}
+
+type podLogResults []podLogResult
+
+func (results podLogResults) listKind() string { return "PodLogList" }
+
+func (results podLogResults) text(w io.Writer, multiNamespace bool) {
+	for _, r := range results {
+		fmt.Fprintf(w, "%s: %s\n", r.source(), r.Log)
+	}
+}
+
+/* table prints one row per log line, so that every line is prefixed with the pod and container it came from */
+func (results podLogResults) table(multiNamespace bool) ([]string, [][]string) {
+	rows := [][]string{}
+	for _, r := range results {
+		for _, line := range strings.Split(strings.TrimRight(r.Log, "\n"), "\n") {
+			rows = append(rows, []string{r.source(), line})
+		}
+	}
+	return []string{"POD", "LOG"}, rows
+}
+
+func (results podLogResults) sortBy(field string) error {
+	byName := func(i, j int) bool { return results[i].Pod < results[j].Pod }
+	return sortResults(results, func(i int) string { return results[i].Namespace }, byName, map[string]func(i, j int) bool{
+		"name":      byName,
+		"pod":       byName,
+		"container": func(i, j int) bool { return results[i].Container < results[j].Container },
+	}, field)
+}
+
+func (results podLogResults) names() []string {
+	names := []string{}
+	for _, r := range results {
+		names = append(names, r.Pod)
+	}
+	return names
+}
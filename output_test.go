@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/* testScaleResults returns getScale results for a deployment and a statefulset in dev-1 and a daemonset in dev-2 */
+func testScaleResults() scaleResults {
+	return scaleResults{
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "payments-api", Replicas: 2, CurrentReplicas: 2},
+		{Namespace: "dev-1", Kind: kindStatefulSet, Name: "payments-db", Replicas: 3, CurrentReplicas: 3},
+		{Namespace: "dev-2", Kind: kindDaemonSet, Name: "log-shipper", Replicas: 4, CurrentReplicas: 1},
+	}
+}
+
+/*
+	Unit test printResults
+*/
+
+//Tests the default format for a single namespace. Should print kubeToggler's original "name: scale" lines
+func TestPrintResults_Text(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, "", testScaleResults()[:2], false)
+	exOut := "payments-api: 2\nstatefulset/payments-db: 3\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect text, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests the default format of getName across namespaces. Should print one "namespace: names" line per namespace
+func TestPrintResults_TextNamesMultiNamespace(t *testing.T) {
+	out := new(bytes.Buffer)
+	results := nameResults{
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "payments-api"},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "payments-worker"},
+		{Namespace: "dev-3", Kind: kindDeployment, Name: "payments-api"},
+	}
+	err := printResults(out, "", results, true)
+	exOut := "dev-1: payments-api payments-worker \ndev-3: payments-api \n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect text, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o json. Should print a ScaleList document with one item per workload
+func TestPrintResults_JSON(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, outputJSON, testScaleResults()[:1], false)
+	exOut := `{
+    "kind": "ScaleList",
+    "items": [
+        {
+            "namespace": "dev-1",
+            "kind": "Deployment",
+            "name": "payments-api",
+            "replicas": 2,
+            "currentReplicas": 2,
+            "readyReplicas": 0
+        }
+    ]
+}
+`
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect json, got: %v, want: %v, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o json without results. Should print an empty items list rather than null
+func TestPrintResults_JSONEmpty(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, outputJSON, nameResults{}, false)
+	exOut := "{\n    \"kind\": \"NameList\",\n    \"items\": []\n}\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect json, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o yaml. Should print the same document as json in YAML
+func TestPrintResults_YAML(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, outputYAML, countResults{{Namespace: "dev-1", Count: 2}}, false)
+	exOut := "items:\n- count: 2\n  namespace: dev-1\nkind: CountList\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect yaml, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o table across namespaces. Should print aligned columns with a NAMESPACE column and daemonset scales as desired/current
+func TestPrintResults_Table(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, outputTable, testScaleResults(), true)
+	exOut := "NAMESPACE   NAME                      SCALE\n" +
+		"dev-1       payments-api              2\n" +
+		"dev-1       statefulset/payments-db   3\n" +
+		"dev-2       daemonset/log-shipper     4/1\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect table, got:\n%v\nwant:\n%v\nerror: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o table for logs. Should print one row per log line
+func TestPrintResults_TableLogs(t *testing.T) {
+	results := podLogResults{{Namespace: "dev-1", Pod: "web-1", Log: "started\nready\n"}}
+	exOut := [][]string{{"web-1", "started"}, {"web-1", "ready"}}
+	_, rows := results.table(false)
+	if !reflect.DeepEqual(rows, exOut) {
+		t.Errorf("Returned incorrect rows, got: %v, want: %v", rows, exOut)
+	}
+}
+
+//Tests -o name. Should print one name per line
+func TestPrintResults_Name(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, outputName, testScaleResults(), true)
+	exOut := "payments-api\nstatefulset/payments-db\ndaemonset/log-shipper\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect names, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o name for getNumWithLabels, whose results have no names. Should return an error
+func TestPrintResults_NameUnsupported(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := printResults(out, outputName, countResults{{Namespace: "dev-1", Count: 2}}, false)
+	if err == nil {
+		t.Errorf("Expected error for -o name on counts, got: %q, error: %v", out.String(), err)
+	}
+}
+
+//Tests -o go-template against the typed results. Should print only the workloads scaled to 0
+func TestPrintResults_GoTemplate(t *testing.T) {
+	out := new(bytes.Buffer)
+	results := scaleResults{{Kind: kindDeployment, Name: "payments-api", Replicas: 0}, {Kind: kindDeployment, Name: "search-api", Replicas: 2}}
+	err := printResults(out, `go-template={{range .Items}}{{if eq .Replicas 0}}{{.Name}}{{"\n"}}{{end}}{{end}}`, results, false)
+	exOut := "payments-api\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Printed incorrect template output, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests -o jsonpath with a filter on the json field names and a path without braces. Should print the matching names and labels
+func TestPrintResults_JSONPath(t *testing.T) {
+	results := scaleResults{
+		{Kind: kindDeployment, Name: "payments-api", Replicas: 0, Labels: map[string]string{"team": "payments"}},
+		{Kind: kindDeployment, Name: "search-api", Replicas: 2, Labels: map[string]string{"team": "search"}},
+	}
+	cases := map[string]string{
+		"jsonpath={.items[?(@.replicas==0)].name}": "payments-api",
+		"jsonpath=.items[*].labels.team":           "payments search",
+	}
+	for format, exOut := range cases {
+		out := new(bytes.Buffer)
+		err := printResults(out, format, results, false)
+		if err != nil || out.String() != exOut {
+			t.Errorf("Printed incorrect jsonpath output for %v, got: %q, want: %q, error: %v", format, out.String(), exOut, err)
+		}
+	}
+}
+
+//Tests checkOutputFormat. Should accept the known formats and parseable templates and reject anything else
+func TestCheckOutputFormat(t *testing.T) {
+	for _, format := range []string{"", outputJSON, outputYAML, outputTable, outputName, "go-template={{.Kind}}", "jsonpath={.kind}"} {
+		if err := checkOutputFormat(format); err != nil {
+			t.Errorf("Rejected output format %q, error: %v", format, err)
+		}
+	}
+	for _, format := range []string{"xml", "go-template={{.Kind", "jsonpath={.items[}"} {
+		if err := checkOutputFormat(format); err == nil {
+			t.Errorf("Expected error for output format %q", format)
+		}
+	}
+}
+
+/*
+	Integration test GetScales
+*/
+
+//Tests the typed getScale results. Should return the desired, current and ready replicas and the labels of the deployment
+func TestGetScales_Results(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	exOut := scaleResults{{
+		Namespace:       namespace,
+		Kind:            kindDeployment,
+		Name:            "otherconnector-connector",
+		Replicas:        2,
+		CurrentReplicas: 2,
+		ReadyReplicas:   2,
+		Labels:          map[string]string{"expose.name": "usmc2", "expose.group": "usmc"},
+	}}
+	out, err := toggler.GetScales(kindDeployment, nil, []string{"otherconnector-connector"}, namespace)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect results, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+/*
+	Unit test sortBy
+*/
+
+//Tests the default sort. Should order results by namespace and then by printed name
+func TestSortBy_Default(t *testing.T) {
+	results := scaleResults{
+		{Namespace: "dev-2", Kind: kindDeployment, Name: "api"},
+		{Namespace: "dev-1", Kind: kindStatefulSet, Name: "db"},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "web"},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "api"},
+	}
+	out := new(bytes.Buffer)
+	err := results.sortBy("")
+	printResults(out, outputName, results, true)
+	exOut := "api\nstatefulset/db\nweb\napi\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Sorted incorrectly, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests --sort-by scale. Should order results by replicas and keep equal scales ordered by name
+func TestSortBy_Scale(t *testing.T) {
+	results := scaleResults{
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "web", Replicas: 3},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "worker", Replicas: 0},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "api", Replicas: 3},
+	}
+	out := new(bytes.Buffer)
+	err := results.sortBy(".scale")
+	printResults(out, "", results, false)
+	exOut := "worker: 0\napi: 3\nweb: 3\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Sorted incorrectly, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests --sort-by scale across namespaces. Should sort within each namespace and keep every namespace in a single group
+func TestSortBy_ScaleMultiNamespace(t *testing.T) {
+	results := scaleResults{
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "a", Replicas: 3},
+		{Namespace: "dev-2", Kind: kindDeployment, Name: "b", Replicas: 1},
+		{Namespace: "dev-1", Kind: kindDeployment, Name: "c", Replicas: 0},
+	}
+	out := new(bytes.Buffer)
+	err := results.sortBy("scale")
+	printResults(out, "", results, true)
+	exOut := "dev-1:\n  c: 0\n  a: 3\ndev-2:\n  b: 1\n"
+	if err != nil || out.String() != exOut {
+		t.Errorf("Sorted incorrectly, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests --sort-by count. Should order the namespaces by their counts
+func TestSortBy_Count(t *testing.T) {
+	results := countResults{{Namespace: "dev-1", Count: 4}, {Namespace: "dev-2", Count: 1}}
+	err := results.sortBy("count")
+	if err != nil || results[0].Namespace != "dev-2" {
+		t.Errorf("Sorted incorrectly, got: %+v, error: %v", results, err)
+	}
+}
+
+//Tests --sort-by age and restarts on pod lifetimes. Should put the oldest pod, or the pod with the fewest restarts, first
+func TestSortBy_PodFields(t *testing.T) {
+	now := time.Now()
+	results := podLifetimeResults{
+		{Pod: "a", Created: now.Add(-time.Minute), Restarts: 5},
+		{Pod: "b", Created: now.Add(-time.Hour), Restarts: 0},
+		{Pod: "c", Created: now.Add(-2 * time.Minute), Restarts: 1},
+	}
+	cases := map[string][]string{"age": {"b", "c", "a"}, "restarts": {"b", "c", "a"}, "name": {"a", "b", "c"}}
+	for field, exOut := range cases {
+		err := results.sortBy(field)
+		if out := results.names(); err != nil || !reflect.DeepEqual(out, exOut) {
+			t.Errorf("Sorted incorrectly by %v, got: %v, want: %v, error: %v", field, out, exOut, err)
+		}
+	}
+}
+
+//Tests --sort-by with a field the results don't have. Should return an error listing the valid fields
+func TestSortBy_UnknownField(t *testing.T) {
+	err := countResults{{Namespace: "dev-1", Count: 1}}.sortBy("restarts")
+	if err == nil || err.Error() != `error: cannot sort by "restarts", must be one of count, namespace` {
+		t.Errorf("Expected error for unknown sort field, got: %v", err)
+	}
+}
+
+/*
+	Integration test doCommand output
+*/
+
+//Tests getScale end to end on deployments created out of order. Should print them sorted by name
+func TestDoCommand_GetScaleSorted(t *testing.T) {
+	toggler, _, out := newBufferedToggler(
+		newDeployment("zeta", namespace, map[string]string{"team": "a"}, 2),
+		newDeployment("alpha", namespace, map[string]string{"team": "a"}, 1),
+		newStatefulSet("mid", namespace, map[string]string{"team": "a"}, 0),
+	)
+	doCommand(toggler, parseArgs([]string{"kubeToggler", "getScale", "team=a", namespace}))
+	exOut := "alpha: 1\nstatefulset/mid: 0\nzeta: 2\n"
+	if out.String() != exOut {
+		t.Errorf("Printed incorrect output, got: %q, want: %q", out.String(), exOut)
+	}
+}
+
+//Tests getScale end to end with --sort-by scale and -o table
+func TestDoCommand_GetScaleSortByTable(t *testing.T) {
+	toggler, _, out := newBufferedToggler(
+		newDeployment("zeta", namespace, map[string]string{"team": "a"}, 2),
+		newDeployment("alpha", namespace, map[string]string{"team": "a"}, 3),
+		newDeployment("beta", namespace, map[string]string{"team": "a"}, 2),
+	)
+	doCommand(toggler, parseArgs([]string{"kubeToggler", "getScale", "team=a", namespace, "--sort-by", "scale", "-o", "table"}))
+	exOut := "NAME    SCALE\nbeta    2\nzeta    2\nalpha   3\n"
+	if out.String() != exOut {
+		t.Errorf("Printed incorrect output, got: %q, want: %q", out.String(), exOut)
+	}
+}
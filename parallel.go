@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+/* defaultConcurrency is the number of objects a Toggler works on at the same time unless --concurrency says otherwise */
+const defaultConcurrency = 10
+
+/* The default client-side rate limit, see --qps and --burst. client-go's own defaults of 5 requests per second with a burst of 10
+   would throttle a worker pool of defaultConcurrency right away */
+const (
+	defaultQPS   = 50
+	defaultBurst = 100
+)
+
+/* checkConcurrency returns an error if a --concurrency value can't run anything */
+func checkConcurrency(concurrency int) error {
+	if concurrency < 1 {
+		return errors.New("error: --concurrency must be at least 1")
+	}
+	return nil
+}
+
+/* forEach calls fn for every index from 0 to n-1, running up to the Toggler's concurrency calls at the same time. An error doesn't
+   stop the other calls, and every error is returned in index order */
+func (t *Toggler) forEach(n int, fn func(worker *Toggler, i int) error) error {
+	workers := t.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	outs := make([]bytes.Buffer, n)
+	errs := make([]error, n)
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+
+				//Each call gets a copy of the Toggler with its own output buffer, written out in index order once every call is done,
+				//so that the output reads the same as a sequential loop's
+				worker := *t
+				worker.out = &outs[i]
+				errs[i] = fn(&worker, i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := range outs {
+		if _, err := t.out.Write(outs[i].Bytes()); err != nil {
+			return err
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+/* joinErrors combines the errors of the steps of an operation, any of which may be nil or an aggregate itself, into one flat
+   utilerrors.Aggregate. It returns nil if every error is nil */
+func joinErrors(errs ...error) error {
+	agg := utilerrors.NewAggregate(errs)
+	if agg == nil {
+		return nil
+	}
+	return utilerrors.Flatten(agg)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+/*
+	Unit test forEach
+*/
+
+//Tests output and errors from parallel calls. Should print the output in index order and return every error in index order
+func TestForEach_DeterministicOrder(t *testing.T) {
+	toggler, _, out := newBufferedToggler()
+	err := toggler.forEach(6, func(worker *Toggler, i int) error {
+		//Later indexes finish first
+		time.Sleep(time.Duration(6-i) * time.Millisecond)
+		fmt.Fprintf(worker.out, "%d\n", i)
+		if i%2 == 1 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+	exOut := "0\n1\n2\n3\n4\n5\n"
+	agg, ok := err.(utilerrors.Aggregate)
+	if out.String() != exOut || !ok || len(agg.Errors()) != 3 || agg.Errors()[0].Error() != "error 1" || agg.Errors()[2].Error() != "error 5" {
+		t.Errorf("Returned incorrect results, got: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+}
+
+//Tests the number of calls running at the same time. Should never be more than the Toggler's concurrency
+func TestForEach_BoundedConcurrency(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	toggler.concurrency = 3
+	running, most := int32(0), int32(0)
+	err := toggler.forEach(20, func(worker *Toggler, i int) error {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			seen := atomic.LoadInt32(&most)
+			if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	if err != nil || most > 3 || most < 1 {
+		t.Errorf("Ran incorrect number of calls at once, got: %v, want: at most 3, error: %v", most, err)
+	}
+}
+
+/*
+	Integration test parallel scaling
+*/
+
+//Tests setScale on names where one doesn't exist. Should scale the others and return the missing one's error
+func TestSetScales_AggregatesErrors(t *testing.T) {
+	toggler, _ := newFakeToggler(newDeployment("payments-api", namespace, nil, 1), newDeployment("payments-worker", namespace, nil, 1))
+	names := []string{"payments-api", "missing", "payments-worker"}
+	scales, err := toggler.SetScales(kindDeployment, nil, names, 4, namespace)
+	if err == nil || len(scales) != 2 || scales[0].Name != "payments-api" || scales[1].Name != "payments-worker" {
+		t.Errorf("Returned incorrect scales, got: %v, error: %v", scales, err)
+	}
+	if replicas := *getDeployment(t, toggler, "payments-worker").Spec.Replicas; replicas != 4 {
+		t.Errorf("Stopped at the first error, got replicas: %v, want: 4", replicas)
+	}
+}
+
+//Tests the --concurrency value. Should reject values below 1
+func TestCheckConcurrency(t *testing.T) {
+	if err := checkConcurrency(0); err == nil {
+		t.Errorf("Expected error for --concurrency 0")
+	}
+	osArgs := []string{"kubeToggler", "getScale", "app=web", "myNamespace", "--concurrency", "32"}
+	if args := parseArgs(osArgs); args.concurrency != 32 {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/* protectedAnnotation opts a workload out of being stopped, scaled or restarted by kubeToggler when it is set to true */
+const protectedAnnotation = "kubetoggler.io/protected"
+
+/* protectionOverriddenReason is the reason of the event recorded on a protected workload that was changed with --force */
+const protectionOverriddenReason = "ProtectionOverridden"
+
+/* isProtected returns true if a workload's protectedAnnotation is set to a true value */
+func isProtected(w workload) bool {
+	protected, err := strconv.ParseBool(w.annotations[protectedAnnotation])
+	return err == nil && protected
+}
+
+/* apiVersionFor returns the API version of the given kind, for object references */
+func apiVersionFor(kind string) string {
+	if kind == kindCronJob {
+		return "batch/v1"
+	}
+	return "apps/v1"
+}
+
+/* currentUser returns the name of the local user running kubeToggler, for the audit event */
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+/* unprotected returns the names of the workloads of the given kind that may be changed. Workloads that can't be looked up are left
+   out too, recorded as failed, and their errors returned alongside the names that could be checked. Protected workloads are left out with a
+   "name: skipped (protected)" line. With force set they are kept instead and the override is printed, see recordForce for its event */
+func (t *Toggler) unprotected(kind string, names []string, namespace string) ([]string, error) {
+	keep := make([]bool, len(names))
+	err := t.forEach(len(names), func(worker *Toggler, i int) error {
+		w, err := worker.getWorkload(kind, names[i], namespace)
+		if err != nil {
+			return worker.recordResult(kind, names[i], namespace, err)
+		}
+		if !isProtected(w) {
+			keep[i] = true
+			return nil
+		}
+		if !worker.force {
+			worker.recordSkip(kind, names[i], namespace, "protected")
+			return nil
+		}
+		fmt.Fprintf(worker.out, "%s: protected, changed anyway with --force%s\n", displayName(kind, names[i]), worker.dryRunSuffix())
+		keep[i] = true
+		return nil
+	})
+
+	allowed := []string{}
+	for i, n := range names {
+		if keep[i] {
+			allowed = append(allowed, n)
+		}
+	}
+	return allowed, err
+}
+
+/* recordForce records a Warning event on a workload that was changed with --force if it is protected, saying who changed it, so that
+   it shows up in `kubectl describe`. recordResult calls it once the change has succeeded. Nothing is recorded in a dry run */
+func (t *Toggler) recordForce(kind string, name string, namespace string) error {
+	if !t.force || t.dryRun != "" {
+		return nil
+	}
+	w, err := t.getWorkload(kind, name, namespace)
+	if err != nil || !isProtected(w) {
+		return err
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: name + ".", Namespace: namespace},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: apiVersionFor(kind),
+			Kind:       kind,
+			Namespace:  namespace,
+			Name:       name,
+		},
+		Reason:         protectionOverriddenReason,
+		Message:        fmt.Sprintf("%s overrode %s=true with kubeToggler --force", currentUser(), protectedAnnotation),
+		Type:           corev1.EventTypeWarning,
+		Source:         corev1.EventSource{Component: "kubeToggler"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err = t.clientset.CoreV1().Events(namespace).Create(context.Background(), event, metav1.CreateOptions{})
+	return err
+}
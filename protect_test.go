@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/* newProtectedDeployment returns a deployment fixture annotated kubetoggler.io/protected=true */
+func newProtectedDeployment(name string, labels map[string]string, replicas int32) *appsv1.Deployment {
+	deployment := newDeployment(name, namespace, labels, replicas)
+	deployment.Annotations = map[string]string{protectedAnnotation: "true"}
+	return deployment
+}
+
+/* protectObjects returns an ingress controller that is protected and an app that is not, both labelled env=test */
+func protectObjects() []runtime.Object {
+	return []runtime.Object{
+		newProtectedDeployment("ingress-controller", map[string]string{"env": "test"}, 2),
+		newDeployment("app", namespace, map[string]string{"env": "test"}, 2),
+	}
+}
+
+/*
+	Integration test protected workloads
+*/
+
+//Tests setScale on a label that matches a protected deployment. Should scale only the other deployment and report the skip
+func TestSetScales_SkipsProtected(t *testing.T) {
+	toggler, _, out := newBufferedToggler(protectObjects()...)
+	scales, err := toggler.SetScales(kindDeployment, labels.SelectorFromSet(map[string]string{"env": "test"}), nil, 0, namespace)
+	exOut := "ingress-controller: skipped (protected)\napp: 2 -> 0\n"
+	if err != nil || out.String() != exOut || len(scales) != 1 || scales[0].Name != "app" {
+		t.Errorf("Scaled incorrectly, got output: %q, want: %q, scales: %v, error: %v", out.String(), exOut, scales, err)
+	}
+	if replicas := *getDeployment(t, toggler, "ingress-controller").Spec.Replicas; replicas != 2 {
+		t.Errorf("Scaled protected deployment, got replicas: %v, want: 2", replicas)
+	}
+}
+
+//Tests toggleOff and a rolling reset on a protected deployment. Should leave it untouched
+func TestToggleOffAndReset_SkipProtected(t *testing.T) {
+	toggler, _, _ := newBufferedToggler(protectObjects()...)
+	_, err1 := toggler.ToggleOff(kindDeployment, nil, []string{"ingress-controller"}, namespace)
+	err2 := toggler.ResetWorkloads(kindDeployment, nil, []string{"ingress-controller"}, resetModeRolling, time.Second, namespace)
+	deployment := getDeployment(t, toggler, "ingress-controller")
+	if _, restarted := deployment.Spec.Template.Annotations[restartedAtAnnotation]; err1 != nil || err2 != nil || *deployment.Spec.Replicas != 2 || restarted {
+		t.Errorf("Changed protected deployment, got: %+v, errors: %v, %v", deployment.Spec, err1, err2)
+	}
+}
+
+//Tests toggleOff with --force. Should scale the protected deployment and record a Warning event on it
+func TestToggleOff_ForceAudited(t *testing.T) {
+	toggler, _, out := newBufferedToggler(protectObjects()...)
+	toggler.force = true
+	_, err := toggler.ToggleOff(kindDeployment, nil, []string{"ingress-controller"}, namespace)
+	exOut := "ingress-controller: protected, changed anyway with --force\ningress-controller: 2 -> 0\n"
+	if err != nil || out.String() != exOut || *getDeployment(t, toggler, "ingress-controller").Spec.Replicas != 0 {
+		t.Errorf("Toggled off incorrectly, got output: %q, want: %q, error: %v", out.String(), exOut, err)
+	}
+
+	events, err := toggler.clientset.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil || len(events.Items) != 1 || events.Items[0].Reason != protectionOverriddenReason || events.Items[0].InvolvedObject.Name != "ingress-controller" {
+		t.Errorf("Expected one %v event, got: %+v, error: %v", protectionOverriddenReason, events, err)
+	}
+}
+
+//Tests toggleOff with --force on a protected deployment that can't be scaled. Should not record an event for a change that never happened
+func TestToggleOff_ForceFailed(t *testing.T) {
+	toggler, clientset, _ := newBufferedToggler(protectObjects()...)
+	toggler.force = true
+	failWrites(clientset, "update", "deployments", 10, errors.New("connection refused"))
+	_, err := toggler.ToggleOff(kindDeployment, nil, []string{"ingress-controller"}, namespace)
+	events, _ := toggler.clientset.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
+	if err == nil || len(events.Items) != 0 {
+		t.Errorf("Toggled off incorrectly, got events: %+v, error: %v", events.Items, err)
+	}
+}
+
+//Tests --force in a dry run. Should report the override without recording an event
+func TestToggleOff_ForceDryRun(t *testing.T) {
+	toggler, _, out := newBufferedToggler(protectObjects()...)
+	toggler.force = true
+	toggler.dryRun = dryRunClient
+	_, err := toggler.ToggleOff(kindDeployment, nil, []string{"ingress-controller"}, namespace)
+	exOut := "ingress-controller: protected, changed anyway with --force (dry run)\ningress-controller: 2 -> 0 (dry run)\n"
+	events, _ := toggler.clientset.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil || out.String() != exOut || len(events.Items) != 0 {
+		t.Errorf("Toggled off incorrectly, got output: %q, want: %q, events: %v, error: %v", out.String(), exOut, len(events.Items), err)
+	}
+}
+
+/*
+	Unit test isProtected
+*/
+
+//Tests reading the protected annotation. Should accept true values only
+func TestIsProtected(t *testing.T) {
+	cases := map[string]bool{"true": true, "True": true, "1": true, "false": false, "yes": false, "": false}
+	for value, want := range cases {
+		w := workload{annotations: map[string]string{protectedAnnotation: value}}
+		if out := isProtected(w); out != want {
+			t.Errorf("Returned incorrect protection for %q, got: %v, want: %v", value, out, want)
+		}
+	}
+}
+
+//Tests the --force flag. Should set force on the kubeCmd
+func TestParseArgs_Force(t *testing.T) {
+	osArgs := []string{"kubeToggler", "toggleOff", "app=web", "myNamespace", "--force"}
+	if args := parseArgs(osArgs); !args.force {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+/* The outcomes of a single object in a mutating command */
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeSkipped   = "skipped"
+)
+
+/* The exit codes of a mutating command. exitFailure is also what log.Fatalln exits with when a command fails before changing anything */
+const (
+	exitSuccess        = 0
+	exitFailure        = 1
+	exitPartialFailure = 2
+)
+
+/* objectResult is the outcome of a single object in a mutating command, with the reason it failed or was skipped */
+type objectResult struct {
+	namespace string
+	kind      string
+	name      string
+	outcome   string
+	reason    string
+}
+
+/* report collects the outcome of every object a mutating command attempts. It is shared by the workers of forEach, so results are
+   added under a lock. A nil report records nothing, which is how a Toggler used as a library behaves */
+type report struct {
+	mu      sync.Mutex
+	results []objectResult
+}
+
+/* add records the outcome of a single object */
+func (r *report) add(result objectResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+/* count returns the number of objects with the given outcome */
+func (r *report) count(outcome string) int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, result := range r.results {
+		if result.outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+/* summary returns the line printed at the end of a mutating command, e.g. "toggleOff: 8 succeeded, 1 failed, 1 skipped" */
+func (r *report) summary(cmd string) string {
+	return fmt.Sprintf("%s: %d succeeded, %d failed, %d skipped", cmd, r.count(outcomeSucceeded), r.count(outcomeFailed), r.count(outcomeSkipped))
+}
+
+/* exitCode returns exitSuccess if nothing failed, exitFailure if nothing succeeded and exitPartialFailure otherwise. Skipped objects
+   don't count as failures, and otherErrors are failures that don't belong to any one object, like a rollout that timed out */
+func (r *report) exitCode(otherErrors int) int {
+	failed := r.count(outcomeFailed) + otherErrors
+	switch {
+	case failed == 0:
+		return exitSuccess
+	case r.count(outcomeSucceeded) == 0:
+		return exitFailure
+	default:
+		return exitPartialFailure
+	}
+}
+
+/* objectError is the error of a single object, already printed and recorded by recordResult */
+type objectError struct {
+	kind string
+	name string
+	err  error
+}
+
+func (e objectError) Error() string {
+	return fmt.Sprintf("%s: %v", displayName(e.kind, e.name), e.err)
+}
+
+/* recordResult records whether the object of the given kind and name was changed. A failure is printed as a "name: failed: reason"
+   line and returned as an objectError, so that it isn't reported a second time. The line for a success is printed by the operation
+   itself, since it knows what changed */
+func (t *Toggler) recordResult(kind string, name string, namespace string, err error) error {
+	if err == nil {
+		t.report.add(objectResult{namespace: namespace, kind: kind, name: name, outcome: outcomeSucceeded})
+
+		//The change went through, so a failed audit event is only printed
+		if err := t.recordForce(kind, name, namespace); err != nil {
+			fmt.Fprintf(t.out, "%s: changed, but the %s event could not be recorded: %v\n", displayName(kind, name), protectionOverriddenReason, err)
+		}
+		return nil
+	}
+	fmt.Fprintf(t.out, "%s: failed: %v\n", displayName(kind, name), err)
+	t.report.add(objectResult{namespace: namespace, kind: kind, name: name, outcome: outcomeFailed, reason: err.Error()})
+	return objectError{kind: kind, name: name, err: err}
+}
+
+/* recordSkip prints a "name: skipped (reason)" line for an object that is left alone on purpose and records it */
+func (t *Toggler) recordSkip(kind string, name string, namespace string, reason string) {
+	fmt.Fprintf(t.out, "%s: skipped (%s)\n", displayName(kind, name), reason)
+	t.report.add(objectResult{namespace: namespace, kind: kind, name: name, outcome: outcomeSkipped, reason: reason})
+}
+
+/* unreported returns the errors of a command that aren't objectErrors, and so haven't been printed with the object they belong to */
+func unreported(errs []error) []error {
+	others := []error{}
+	agg := utilerrors.NewAggregate(errs)
+	if agg == nil {
+		return others
+	}
+	for _, err := range utilerrors.Flatten(agg).Errors() {
+		if _, ok := err.(objectError); !ok {
+			others = append(others, err)
+		}
+	}
+	return others
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/* reportObjects returns two deployments and a protected one */
+func reportObjects() []runtime.Object {
+	return []runtime.Object{
+		newDeployment("payments-api", namespace, nil, 2),
+		newDeployment("payments-worker", namespace, nil, 1),
+		newProtectedDeployment("ingress-controller", nil, 2),
+	}
+}
+
+/*
+	Unit test report
+*/
+
+//Tests the exit code of different outcomes. Should tell all-success, partial failure and total failure apart, ignoring skips
+func TestReport_ExitCode(t *testing.T) {
+	cases := []struct {
+		outcomes    []string
+		otherErrors int
+		exCode      int
+	}{
+		{[]string{outcomeSucceeded, outcomeSkipped}, 0, exitSuccess},
+		{[]string{outcomeSucceeded, outcomeFailed}, 0, exitPartialFailure},
+		{[]string{outcomeSucceeded}, 1, exitPartialFailure},
+		{[]string{outcomeFailed, outcomeSkipped}, 0, exitFailure},
+		{[]string{}, 1, exitFailure},
+		{[]string{}, 0, exitSuccess},
+	}
+	for _, c := range cases {
+		r := &report{}
+		for _, outcome := range c.outcomes {
+			r.add(objectResult{outcome: outcome})
+		}
+		if code := r.exitCode(c.otherErrors); code != c.exCode {
+			t.Errorf("Returned incorrect exit code for %v and %d other error(s), got: %v, want: %v", c.outcomes, c.otherErrors, code, c.exCode)
+		}
+	}
+}
+
+//Tests unreported on a mix of object and other errors. Should return only the other errors, flattened
+func TestUnreported(t *testing.T) {
+	timeout := errors.New("error: timed out")
+	errs := []error{nil, joinErrors(objectError{kind: kindDeployment, name: "payments-api", err: errors.New("conflict")}, timeout)}
+	if out := unreported(errs); len(out) != 1 || out[0] != timeout {
+		t.Errorf("Returned incorrect errors, got: %v, want: [%v]", out, timeout)
+	}
+}
+
+/*
+	Integration test partial failures
+*/
+
+//Tests toggleOff on a missing, a protected and two existing deployments. Should change every one it can and report each outcome
+func TestToggleOff_PartialFailure(t *testing.T) {
+	toggler, _, out := newBufferedToggler(reportObjects()...)
+	toggler.report = &report{}
+	names := []string{"payments-api", "missing", "ingress-controller", "payments-worker"}
+	scales, err := toggler.ToggleOff(kindDeployment, nil, names, namespace)
+	exOut := "missing: failed: deployments.apps \"missing\" not found\n" +
+		"ingress-controller: skipped (protected)\n" +
+		"payments-api: 2 -> 0\n" +
+		"payments-worker: 1 -> 0\n"
+	if err == nil || len(scales) != 2 || out.String() != exOut {
+		t.Errorf("Toggled off incorrectly, got output: %q, want: %q, scales: %v, error: %v", out.String(), exOut, scales, err)
+	}
+	exSummary := "toggleOff: 2 succeeded, 1 failed, 1 skipped"
+	if summary := toggler.report.summary("toggleOff"); summary != exSummary || toggler.report.exitCode(len(unreported([]error{err}))) != exitPartialFailure {
+		t.Errorf("Returned incorrect summary, got: %q, want: %q", summary, exSummary)
+	}
+}
+
+//Tests a rolling reset where one deployment is missing. Should still restart the other one
+func TestResetWorkloads_PartialFailure(t *testing.T) {
+	toggler, _, out := newBufferedToggler(reportObjects()...)
+	toggler.report = &report{}
+	err := toggler.ResetWorkloads(kindDeployment, nil, []string{"missing", "payments-api"}, resetModeRolling, time.Second, namespace)
+	if _, restarted := getDeployment(t, toggler, "payments-api").Spec.Template.Annotations[restartedAtAnnotation]; err == nil || !restarted {
+		t.Errorf("Reset incorrectly, got output: %q, error: %v", out.String(), err)
+	}
+	if toggler.report.count(outcomeSucceeded) != 1 || toggler.report.count(outcomeFailed) != 1 {
+		t.Errorf("Returned incorrect report, got: %v", toggler.report.summary("reset"))
+	}
+}
+
+//Tests a successful toggleOff through doCommand. Should end with the summary line
+func TestDoCommand_Summary(t *testing.T) {
+	toggler, _, out := newBufferedToggler(reportObjects()...)
+	toggler.report = &report{}
+	doCommand(toggler, kubeCmd{cmd: "toggleOff", kinds: []string{kindDeployment}, names: []string{"payments-api"}, namespace: namespace, yes: true})
+	if !strings.HasSuffix(out.String(), "payments-api: 2 -> 0\ntoggleOff: 1 succeeded, 0 failed, 0 skipped\n") {
+		t.Errorf("Printed incorrect summary, got: %q", out.String())
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+/* retryBackoff is how often and how far apart a failed write is retried: 5 attempts, 100ms apart at first and doubling each time */
+var retryBackoff = wait.Backoff{Steps: 5, Duration: 100 * time.Millisecond, Factor: 2, Jitter: 0.1}
+
+/* retriable returns true for the errors a write is retried on. A 409 Conflict means another writer, such as an HPA or a GitOps
+   controller, changed the object since it was read, and is retried against the object's latest version the way
+   retry.RetryOnConflict does. 429 Too Many Requests, 5xx errors and timeouts are transient and retried after backing off */
+func retriable(err error) bool {
+	switch {
+	case apierrors.IsConflict(err), apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err):
+		return true
+	case apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err), apierrors.IsUnexpectedServerError(err):
+		return true
+	}
+	status := apierrors.APIStatus(nil)
+	if errors.As(err, &status) && status.Status().Code >= 500 {
+		return true
+	}
+	netErr := net.Error(nil)
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+/* retry calls fn until it succeeds, fails with an error that isn't retriable, or has been tried retryBackoff.Steps times, and
+   returns its last error. fn has to read whatever it writes again on every call so that a conflict isn't just repeated. With verbose
+   set every failed attempt that is retried is printed as a "name: attempt N failed, retrying: reason" line */
+func (t *Toggler) retry(kind string, name string, fn func() error) error {
+	attempt := 0
+	return retry.OnError(retryBackoff, retriable, func() error {
+		attempt++
+		err := fn()
+		if err != nil && t.verbose && retriable(err) && attempt < retryBackoff.Steps {
+			fmt.Fprintf(t.out, "%s: attempt %d failed, retrying: %v\n", displayName(kind, name), attempt, err)
+		}
+		return err
+	})
+}
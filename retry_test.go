@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func init() {
+	retryBackoff = wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 2}
+}
+
+/* failWrites makes the first 'times' writes with the given verb to the given resource fail with 'err', and returns a pointer to the
+   number of writes attempted */
+func failWrites(clientset *fake.Clientset, verb string, resource string, times int, err error) *int {
+	attempts := 0
+	clientset.PrependReactor(verb, resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts <= times {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return &attempts
+}
+
+/* conflictError returns the 409 Conflict error the API server returns when a deployment changed since it was read */
+func conflictError(name string) error {
+	return apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, name, errors.New("the object has been modified"))
+}
+
+/*
+	Unit test retriable
+*/
+
+//Tests which errors are retried. Should retry conflicts, 429s, 5xxs and timeouts but not client errors
+func TestRetriable(t *testing.T) {
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	cases := map[error]bool{
+		conflictError("payments-api"):                   true,
+		apierrors.NewTooManyRequests("slow down", 1):    true,
+		apierrors.NewServiceUnavailable("unavailable"):  true,
+		apierrors.NewInternalError(errors.New("boom")):  true,
+		apierrors.NewTimeoutError("timed out", 1):       true,
+		apierrors.NewNotFound(gr, "payments-api"):       false,
+		apierrors.NewForbidden(gr, "payments-api", nil): false,
+		errors.New("error: daemonset has no replicas"):  false,
+	}
+	for err, want := range cases {
+		if out := retriable(err); out != want {
+			t.Errorf("Returned incorrect retriable for %v, got: %v, want: %v", err, out, want)
+		}
+	}
+}
+
+/*
+	Integration test retries
+*/
+
+//Tests setScale when the first two updates conflict. Should retry with --verbose lines and then scale the deployment
+func TestSetScale_RetriesConflicts(t *testing.T) {
+	toggler, clientset, out := newBufferedToggler()
+	toggler.verbose = true
+	attempts := failWrites(clientset, "update", "deployments", 2, conflictError("testconnector-connector"))
+
+	_, err := toggler.SetScales(kindDeployment, nil, []string{"testconnector-connector"}, 3, namespace)
+	exOut := "testconnector-connector: attempt 1 failed, retrying: " + conflictError("testconnector-connector").Error() + "\n" +
+		"testconnector-connector: attempt 2 failed, retrying: " + conflictError("testconnector-connector").Error() + "\n" +
+		"testconnector-connector: 1 -> 3\n"
+	if err != nil || *attempts != 3 || out.String() != exOut || *getDeployment(t, toggler, "testconnector-connector").Spec.Replicas != 3 {
+		t.Errorf("Retried incorrectly, got output: %q, want: %q, attempts: %v, error: %v", out.String(), exOut, *attempts, err)
+	}
+}
+
+//Tests setScale when every update is throttled. Should give up after retryBackoff.Steps attempts and return the error
+func TestSetScale_RetriesExhausted(t *testing.T) {
+	toggler, clientset := newFakeToggler()
+	attempts := failWrites(clientset, "update", "deployments", 10, apierrors.NewTooManyRequests("slow down", 0))
+	_, err := toggler.SetScales(kindDeployment, nil, []string{"testconnector-connector"}, 3, namespace)
+	if err == nil || *attempts != retryBackoff.Steps {
+		t.Errorf("Retried incorrectly, got attempts: %v, want: %v, error: %v", *attempts, retryBackoff.Steps, err)
+	}
+}
+
+//Tests a patch that is forbidden. Should fail at once without retrying
+func TestPatchWorkload_NoRetry(t *testing.T) {
+	toggler, clientset := newFakeToggler()
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "testconnector-connector", errors.New("rbac"))
+	attempts := failWrites(clientset, "patch", "deployments", 10, forbidden)
+	err := toggler.restartWorkload(kindDeployment, "testconnector-connector", namespace)
+	if !apierrors.IsForbidden(err) || *attempts != 1 {
+		t.Errorf("Retried incorrectly, got attempts: %v, want: 1, error: %v", *attempts, err)
+	}
+}
+
+//Tests a patch while the API server is briefly unavailable. Should retry until it goes through
+func TestPatchWorkload_RetriesUnavailable(t *testing.T) {
+	toggler, clientset := newFakeToggler()
+	attempts := failWrites(clientset, "patch", "deployments", 1, apierrors.NewServiceUnavailable("unavailable"))
+	err := toggler.restartWorkload(kindDeployment, "testconnector-connector", namespace)
+	if _, restarted := getDeployment(t, toggler, "testconnector-connector").Spec.Template.Annotations[restartedAtAnnotation]; err != nil || *attempts != 2 || !restarted {
+		t.Errorf("Retried incorrectly, got attempts: %v, want: 2, error: %v", *attempts, err)
+	}
+}
+
+//Tests the --verbose flag. Should set verbose on the kubeCmd
+func TestParseArgs_Verbose(t *testing.T) {
+	osArgs := []string{"kubeToggler", "toggleOff", "app=web", "myNamespace", "--verbose"}
+	if args := parseArgs(osArgs); !args.verbose {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
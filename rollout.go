@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+/* The two ways reset can restart a workload. resetModeRolling replaces the pods one by one like `kubectl rollout restart`,
+   resetModeBounce scales the workload to 0 and then back to its original replica count */
+const (
+	resetModeRolling = "rolling"
+	resetModeBounce  = "bounce"
+)
+
+/* restartedAtAnnotation is the pod template annotation kubectl patches to trigger a rolling restart */
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+/* rolloutPollInterval is how often a workload's status is checked while waiting for a rollout */
+var rolloutPollInterval = time.Second
+
+/* rolloutComplete returns true once the controller has seen the latest spec and every replica is updated and available. These are
+   the same checks `kubectl rollout status` makes */
+func rolloutComplete(w workload) bool {
+	return w.generation <= w.observedGeneration &&
+		w.updatedReplicas == w.specReplicas &&
+		w.replicas == w.specReplicas &&
+		w.availableReplicas == w.specReplicas
+}
+
+/* workloadCheck reports whether a workload has reached the state being waited for, along with a short progress message */
+type workloadCheck func(w workload) (done bool, progress string, err error)
+
+/* rolledOut is a workloadCheck that waits for rolloutComplete */
+func rolledOut(w workload) (bool, string, error) {
+	if rolloutComplete(w) {
+		return true, "rolled out", nil
+	}
+	return false, fmt.Sprintf("%d/%d updated, %d available", w.updatedReplicas, w.specReplicas, w.availableReplicas), nil
+}
+
+/* waitForWorkloads polls the workloads of the given kind with the given names until 'check' reports all of them done, printing a
+   "name: progress" line to the Toggler's output whenever a workload's progress changes. Once the timeout has passed it returns an
+   error naming every workload that is not done */
+func (t *Toggler) waitForWorkloads(kind string, names []string, namespace string, timeout time.Duration, check workloadCheck) error {
+	pending := make(map[string]bool)
+	printed := make(map[string]string)
+	for _, n := range names {
+		pending[n] = true
+	}
+
+	err := wait.PollImmediate(rolloutPollInterval, timeout, func() (bool, error) {
+		for _, n := range names {
+			if !pending[n] {
+				continue
+			}
+			w, err := t.getWorkload(kind, n, namespace)
+			if err != nil {
+				return false, err
+			}
+			done, progress, err := check(w)
+			if err != nil {
+				return false, err
+			}
+			if progress != printed[n] {
+				fmt.Fprintf(t.out, "%s: %s\n", displayName(kind, n), progress)
+				printed[n] = progress
+			}
+			if done {
+				delete(pending, n)
+			}
+		}
+		return len(pending) == 0, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		notDone := []string{}
+		for _, n := range names {
+			if pending[n] {
+				notDone = append(notDone, n)
+			}
+		}
+		return fmt.Errorf("error: timed out after %v waiting for %s(s) %s", timeout, strings.ToLower(kind), strings.Join(notDone, ", "))
+	}
+	return err
+}
+
+/* restartWorkload triggers a rolling restart of the workload of the given kind and name by stamping the current time on its pod
+   template, the same way `kubectl rollout restart` does */
+func (t *Toggler) restartWorkload(kind string, name string, namespace string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAtAnnotation: time.Now().Format(time.RFC3339)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return t.patchWorkload(kind, name, types.StrategicMergePatchType, patch, namespace)
+}
+
+/* checkResetMode returns an error if a --mode value is not one of the reset modes */
+func checkResetMode(mode string) error {
+	if mode != resetModeRolling && mode != resetModeBounce {
+		return fmt.Errorf("error: unknown reset mode %q, must be %q or %q", mode, resetModeRolling, resetModeBounce)
+	}
+	return nil
+}
+
+/* ResetDeployments finds the deployments in the given namespace with the given labels or names and restarts them with ResetWorkloads */
+func (t *Toggler) ResetDeployments(selector labels.Selector, names []string, mode string, timeout time.Duration, namespace string) error {
+	return t.ResetWorkloads(kindDeployment, selector, names, mode, timeout, namespace)
+}
+
+/* ResetWorkloads finds the workloads of the given kind in the given namespace with the given labels or names, restarts them in the
+   given reset mode and waits up to 'timeout' until every one reports all of its replicas updated and available */
+func (t *Toggler) ResetWorkloads(kind string, selector labels.Selector, names []string, mode string, timeout time.Duration, namespace string) error {
+	if err := checkResetMode(mode); err != nil {
+		return err
+	}
+	workloadNames, err := t.getNames(kind, selector, names, namespace)
+	if err != nil {
+		return err
+	}
+	workloadNames, err = t.unprotected(kind, workloadNames, namespace)
+	errs := []error{err}
+
+	//A workload that fails doesn't stop the others, and every error is returned
+	restarted := []string{}
+	switch mode {
+	case resetModeRolling:
+		for _, n := range workloadNames {
+			err := t.restartWorkload(kind, n, namespace)
+			if err := t.recordResult(kind, n, namespace, err); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fmt.Fprintf(t.out, "%s: restarted%s\n", displayName(kind, n), t.dryRunSuffix())
+			restarted = append(restarted, n)
+		}
+	case resetModeBounce:
+
+		//Remembers every workload's replica count before scaling it to 0
+		original := make(map[string]int32)
+		stopped := []string{}
+		for _, n := range workloadNames {
+			w, err := t.getWorkload(kind, n, namespace)
+			if err == nil {
+				original[n] = w.specReplicas
+				_, err = t.setScale(kind, n, 0, namespace)
+			}
+			if err != nil {
+				errs = append(errs, t.recordResult(kind, n, namespace, err))
+				continue
+			}
+			stopped = append(stopped, n)
+		}
+
+		//A dry run never scales the workloads down, so it reports the scale back up instead of making it
+		if t.dryRun != "" {
+			for _, n := range stopped {
+				fmt.Fprintf(t.out, "%s: 0 -> %d%s\n", displayName(kind, n), original[n], t.dryRunSuffix())
+				t.recordResult(kind, n, namespace, nil)
+			}
+			return joinErrors(errs...)
+		}
+
+		//The workloads are scaled back up even if their pods didn't all stop in time, rather than being left at 0
+		errs = append(errs, t.waitForWorkloads(kind, stopped, namespace, timeout, t.scaledTo(0)))
+		for _, n := range stopped {
+			_, err := t.setScale(kind, n, original[n], namespace)
+			if err := t.recordResult(kind, n, namespace, err); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			restarted = append(restarted, n)
+		}
+	}
+
+	//Nothing was restarted in a dry run, so there is no rollout to wait for
+	if t.dryRun == "" && len(restarted) > 0 {
+		errs = append(errs, t.waitForWorkloads(kind, restarted, namespace, timeout, rolledOut))
+	}
+	return joinErrors(errs...)
+}
+
+/* scaledTo returns a workloadCheck that waits until the workload runs 'replicas' ready pods. For a scale of 0 it waits until every
+   pod of the workload is gone, including pods that are still terminating */
+func (t *Toggler) scaledTo(replicas int32) workloadCheck {
+	return func(w workload) (bool, string, error) {
+		if replicas == 0 {
+			pods, err := t.getPodsForWorkload(w)
+			if err != nil {
+				return false, "", err
+			}
+			if len(pods) == 0 {
+				return true, "scaled to 0", nil
+			}
+			return false, fmt.Sprintf("%d pod(s) remaining", len(pods)), nil
+		}
+
+		done := w.generation <= w.observedGeneration && w.replicas == replicas && w.readyReplicas == replicas
+		return done, fmt.Sprintf("%d/%d ready", w.readyReplicas, replicas), nil
+	}
+}
+
+/* WaitForScales takes the autoscalingv1.Scale structs returned by SetScales, ToggleOn or ToggleOff for workloads of the given kind and
+   waits up to 'timeout' until every workload runs its new number of ready replicas, or has no pods left if it was scaled to 0 */
+func (t *Toggler) WaitForScales(kind string, scales []*v1.Scale, timeout time.Duration, namespace string) error {
+	checks := make(map[string]workloadCheck)
+	names := []string{}
+	for _, scale := range scales {
+		names = append(names, scale.Name)
+		checks[scale.Name] = t.scaledTo(scale.Spec.Replicas)
+	}
+	return t.waitForWorkloads(kind, names, namespace, timeout, func(w workload) (bool, string, error) {
+		return checks[w.name](w)
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func init() {
+	rolloutPollInterval = 10 * time.Millisecond
+}
+
+/*
+	Unit test rolloutComplete
+*/
+
+//Tests rolloutComplete with old replicas still running and a status the controller has not caught up on
+func TestRolloutComplete(t *testing.T) {
+	deployment := newDeployment("testconnector-connector", namespace, nil, 3)
+	if !rolloutComplete(workloadFromDeployment(deployment)) {
+		t.Errorf("Expected converged deployment to be complete, got status: %+v", deployment.Status)
+	}
+	deployment.Status.Replicas = 4
+	if rolloutComplete(workloadFromDeployment(deployment)) {
+		t.Errorf("Expected deployment with an old replica to be incomplete, got status: %+v", deployment.Status)
+	}
+	deployment = newDeployment("testconnector-connector", namespace, nil, 3)
+	deployment.Generation = 2
+	deployment.Status.ObservedGeneration = 1
+	if rolloutComplete(workloadFromDeployment(deployment)) {
+		t.Errorf("Expected unobserved generation to be incomplete, got generation: %v, status: %+v", deployment.Generation, deployment.Status)
+	}
+}
+
+/*
+	Unit test checkResetMode
+*/
+
+//Tests the --mode values. Should accept rolling and bounce and reject anything else
+func TestCheckResetMode(t *testing.T) {
+	for _, mode := range []string{resetModeRolling, resetModeBounce} {
+		if err := checkResetMode(mode); err != nil {
+			t.Errorf("Returned error for reset mode %v, error: %v", mode, err)
+		}
+	}
+	if err := checkResetMode("bounse"); err == nil {
+		t.Errorf("Expected error for reset mode bounse")
+	}
+}
+
+/*
+	Integration test ResetDeployments
+*/
+
+//Tests a rolling reset. Should stamp the restartedAt annotation on the pod template and keep the replica count
+func TestResetDeployments_Rolling(t *testing.T) {
+	toggler, _ := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 6))
+	err := toggler.ResetDeployments(nil, []string{"testconnector-connector"}, resetModeRolling, time.Second, namespace)
+	deployment := getDeployment(t, toggler, "testconnector-connector")
+	if err != nil || deployment.Spec.Template.Annotations[restartedAtAnnotation] == "" || *deployment.Spec.Replicas != 6 {
+		t.Errorf("Reset incorrectly, got template annotations: %v, replicas: %v, error: %v", deployment.Spec.Template.Annotations, *deployment.Spec.Replicas, err)
+	}
+}
+
+//Tests a bounce reset. Should scale to 0 and then back to the original replica count, not 1
+func TestResetDeployments_Bounce(t *testing.T) {
+	toggler, clientset := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 6))
+	err := toggler.ResetDeployments(nil, []string{"testconnector-connector"}, resetModeBounce, time.Second, namespace)
+	scales := []int32{}
+	for _, action := range clientset.Actions() {
+		if update, ok := action.(k8stesting.UpdateAction); ok && action.GetSubresource() == "scale" {
+			scales = append(scales, update.GetObject().(*autoscalingv1.Scale).Spec.Replicas)
+		}
+	}
+	exScales := []int32{0, 6}
+	if err != nil || !reflect.DeepEqual(scales, exScales) {
+		t.Errorf("Reset incorrectly, got scale updates: %v, want: %v, error: %v", scales, exScales, err)
+	}
+}
+
+//Tests a reset whose pods never become available. Should time out naming the deployment
+func TestResetDeployments_Timeout(t *testing.T) {
+	toggler, clientset := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 2))
+	clientset.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deployment := newDeployment("testconnector-connector", namespace, nil, 2)
+		deployment.Status.AvailableReplicas = 1
+		return true, deployment, clientset.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("deployments"), deployment, namespace)
+	})
+	err := toggler.ResetDeployments(nil, []string{"testconnector-connector"}, resetModeRolling, 50*time.Millisecond, namespace)
+	if err == nil || !strings.Contains(err.Error(), "testconnector-connector") {
+		t.Errorf("Expected timeout error naming testconnector-connector, error: %v", err)
+	}
+}
+
+//Tests an unknown reset mode. Should return an error
+func TestResetDeployments_UnknownMode(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	err := toggler.ResetDeployments(nil, []string{"testconnector-connector"}, "sideways", time.Second, namespace)
+	if err == nil {
+		t.Errorf("Expected error for unknown mode, error: %v", err)
+	}
+}
+
+/*
+	Integration test WaitForScales
+*/
+
+//Tests waiting on a deployment scaled up to 3. Should return once 3 replicas are ready and print its progress
+func TestWaitForScales_ScaleUp(t *testing.T) {
+	toggler, _, out := newBufferedToggler()
+	scales, err1 := toggler.SetDeploymentScales(nil, []string{"testconnector-connector"}, 3, namespace)
+	err2 := toggler.WaitForScales(kindDeployment, scales, time.Second, namespace)
+	exOut := "testconnector-connector: 1 -> 3\ntestconnector-connector: 3/3 ready\n"
+	if err1 != nil || err2 != nil || out.String() != exOut {
+		t.Errorf("Waited incorrectly, got output: %q, want: %q, errors: %v, %v", out.String(), exOut, err1, err2)
+	}
+}
+
+//Tests waiting on two deployments scaled to 0 while one still has a pod. Should time out naming only that deployment
+func TestWaitForScales_PodsRemaining(t *testing.T) {
+	toggler, _, out := newBufferedToggler(
+		newDeployment("testconnector-connector", namespace, nil, 1),
+		newDeployment("otherconnector-connector", namespace, nil, 1),
+		newPod("otherconnector-connector-a", namespace, "otherconnector-connector", time.Now()),
+	)
+	scales, err1 := toggler.SetDeploymentScales(nil, []string{"testconnector-connector", "otherconnector-connector"}, 0, namespace)
+	err2 := toggler.WaitForScales(kindDeployment, scales, 50*time.Millisecond, namespace)
+	exOut := "testconnector-connector: 1 -> 0\notherconnector-connector: 1 -> 0\n" +
+		"testconnector-connector: scaled to 0\notherconnector-connector: 1 pod(s) remaining\n"
+	if err1 != nil || err2 == nil || !strings.HasSuffix(err2.Error(), "deployment(s) otherconnector-connector") || out.String() != exOut {
+		t.Errorf("Waited incorrectly, got output: %q, want: %q, errors: %v, %v", out.String(), exOut, err1, err2)
+	}
+}
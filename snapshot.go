@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+/* snapshotKind is the kind of the document snapshot writes, checked by restore so it doesn't act on some other YAML file */
+const snapshotKind = "Snapshot"
+
+/* snapshot is the document snapshot writes and restore reads back. It records the replica count of every workload of the snapshotted
+   kinds in the snapshotted namespaces, and the namespaces and kinds themselves so restore can tell which workloads appeared since */
+type snapshot struct {
+	Kind       string         `json:"kind"`
+	Created    string         `json:"created"`
+	Namespaces []string       `json:"namespaces"`
+	Kinds      []string       `json:"kinds"`
+	Items      []snapshotItem `json:"items"`
+}
+
+/* snapshotItem is the replica count of a single workload in a snapshot */
+type snapshotItem struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Replicas  int32  `json:"replicas"`
+}
+
+/* restoreChange is a workload whose current replica count differs from the one in a snapshot */
+type restoreChange struct {
+	snapshotItem
+	current int32
+}
+
+/* Snapshot returns the replica counts of every workload of the given kinds (Deployment or StatefulSet) in each of the given namespaces,
+   ordered by namespace, kind and name. A namespace without any workloads is recorded with no items */
+func (t *Toggler) Snapshot(kinds []string, namespaces []string) (snapshot, error) {
+	s := snapshot{Kind: snapshotKind, Created: time.Now().UTC().Format(time.RFC3339), Namespaces: namespaces, Kinds: kinds, Items: []snapshotItem{}}
+	for _, ns := range namespaces {
+		for _, kind := range kinds {
+			workloads, err := t.listWorkloads(kind, nil, ns)
+			if err != nil {
+				return snapshot{}, err
+			}
+			if len(workloads) == 0 {
+				continue
+			}
+			names := []string{}
+			for _, w := range workloads {
+				names = append(names, w.name)
+			}
+			scales, err := t.GetScales(kind, nil, names, ns)
+			if err != nil {
+				return snapshot{}, err
+			}
+			for _, scale := range scales {
+				s.Items = append(s.Items, snapshotItem{Namespace: ns, Kind: kind, Name: scale.Name, Replicas: scale.Replicas})
+			}
+		}
+	}
+	sort.SliceStable(s.Items, func(i, j int) bool {
+		a, b := s.Items[i], s.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return s, nil
+}
+
+/* writeSnapshot writes a snapshot to w as YAML, or as JSON with -o json. restore reads either */
+func writeSnapshot(w io.Writer, s snapshot, format string) error {
+	switch format {
+	case "", outputYAML:
+		out, err := yaml.Marshal(s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(out))
+	case outputJSON:
+		out, err := json.MarshalIndent(s, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(out))
+	default:
+		return fmt.Errorf("error: output format %q is not supported for snapshot, use yaml or json", format)
+	}
+	return nil
+}
+
+/* readSnapshot reads a snapshot written by snapshot from the file at the given path */
+func readSnapshot(path string) (snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	s := snapshot{}
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		return snapshot{}, fmt.Errorf("error: %s is not a kubeToggler snapshot: %v", path, err)
+	}
+	if s.Kind != snapshotKind {
+		return snapshot{}, fmt.Errorf("error: %s is not a kubeToggler snapshot, kind is %q", path, s.Kind)
+	}
+	for _, item := range s.Items {
+		if item.Kind != kindDeployment && item.Kind != kindStatefulSet {
+			return snapshot{}, fmt.Errorf("error: %s holds a %s, only deployments and statefulsets can be restored", path, item.Kind)
+		}
+	}
+	return s, nil
+}
+
+/* DiffSnapshot compares a snapshot with the cluster and returns the workloads whose replica count has changed since. Workloads in the
+   snapshot that no longer exist, and workloads of the snapshotted kinds and namespaces that are not in the snapshot, are drift that
+   restore can't undo, and are returned as messages instead */
+func (t *Toggler) DiffSnapshot(s snapshot) ([]restoreChange, []string, error) {
+	changes := []restoreChange{}
+	drift := []string{}
+	inSnapshot := map[snapshotItem]bool{}
+	for _, item := range s.Items {
+		inSnapshot[snapshotItem{Namespace: item.Namespace, Kind: item.Kind, Name: item.Name}] = true
+
+		w, err := t.getWorkload(item.Kind, item.Name, item.Namespace)
+		if apierrors.IsNotFound(err) {
+			drift = append(drift, fmt.Sprintf("%s: %s: no longer exists", item.Namespace, displayName(item.Kind, item.Name)))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if w.specReplicas != item.Replicas {
+			changes = append(changes, restoreChange{snapshotItem: item, current: w.specReplicas})
+		}
+	}
+
+	for _, ns := range s.Namespaces {
+		for _, kind := range s.Kinds {
+			workloads, err := t.listWorkloads(kind, nil, ns)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, w := range workloads {
+				if !inSnapshot[snapshotItem{Namespace: ns, Kind: kind, Name: w.name}] {
+					drift = append(drift, fmt.Sprintf("%s: %s: not in snapshot, left alone", ns, displayName(kind, w.name)))
+				}
+			}
+		}
+	}
+	return changes, drift, nil
+}
+
+/* printDiff prints the replica counts restore is about to change as "namespace: name: current -> snapshot" lines, followed by any
+   drift */
+func (t *Toggler) printDiff(changes []restoreChange, drift []string) {
+	if len(changes) == 0 {
+		fmt.Fprintln(t.out, "nothing to restore, every replica count matches the snapshot")
+	}
+	for _, c := range changes {
+		fmt.Fprintf(t.out, "%s: %s: %d -> %d\n", c.Namespace, displayName(c.Kind, c.Name), c.current, c.Replicas)
+	}
+	for _, d := range drift {
+		fmt.Fprintf(t.out, "drift: %s\n", d)
+	}
+}
+
+/* restoreTargets groups the changes restore makes by namespace and kind, for confirmTargets */
+func restoreTargets(changes []restoreChange) []namespaceTargets {
+	targets := []namespaceTargets{}
+	for _, c := range changes {
+		last := len(targets) - 1
+		if last >= 0 && targets[last].namespace == c.Namespace && targets[last].kind == c.Kind {
+			targets[last].names = append(targets[last].names, c.Name)
+			continue
+		}
+		targets = append(targets, namespaceTargets{namespace: c.Namespace, kind: c.Kind, names: []string{c.Name}})
+	}
+	return targets
+}
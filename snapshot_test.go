@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/* snapshotObjects returns a deployment and a statefulset in the test namespace */
+func snapshotObjects() []runtime.Object {
+	return []runtime.Object{
+		newDeployment("payments-api", namespace, nil, 2),
+		newStatefulSet("payments-db", namespace, nil, 3),
+	}
+}
+
+/* takeSnapshot snapshots the test namespace and writes it into a temporary file as YAML, returning the snapshot and the file's path */
+func takeSnapshot(t *testing.T, toggler *Toggler) (snapshot, string) {
+	s, err := toggler.Snapshot([]string{kindDeployment, kindStatefulSet}, []string{namespace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := writeSnapshot(out, s, ""); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.yaml")
+	if err := ioutil.WriteFile(path, out.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+/*
+	Integration test snapshot
+*/
+
+//Tests snapshotting a namespace. Should record every deployment and statefulset with its replicas
+func TestSnapshot(t *testing.T) {
+	toggler, _, _ := newBufferedToggler(snapshotObjects()...)
+	s, path := takeSnapshot(t, toggler)
+	exItems := []snapshotItem{
+		{Namespace: namespace, Kind: kindDeployment, Name: "payments-api", Replicas: 2},
+		{Namespace: namespace, Kind: kindStatefulSet, Name: "payments-db", Replicas: 3},
+	}
+	if s.Kind != snapshotKind || !reflect.DeepEqual(s.Items, exItems) {
+		t.Errorf("Returned incorrect snapshot, got: %+v, want: %+v", s.Items, exItems)
+	}
+
+	read, err := readSnapshot(path)
+	if err != nil || !reflect.DeepEqual(read, s) {
+		t.Errorf("Read incorrect snapshot, got: %+v, want: %+v, error: %v", read, s, err)
+	}
+}
+
+/*
+	Integration test restore
+*/
+
+//Tests diffing a snapshot after scaling, deleting and adding workloads. Should return the scaled one as a change and the others as drift
+func TestDiffSnapshot_ChangesAndDrift(t *testing.T) {
+	toggler, _, _ := newBufferedToggler(snapshotObjects()...)
+	s, _ := takeSnapshot(t, toggler)
+	if _, err := toggler.SetScales(kindDeployment, nil, []string{"payments-api"}, 0, namespace); err != nil {
+		t.Fatal(err)
+	}
+	clientset := toggler.clientset
+	clientset.AppsV1().StatefulSets(namespace).Delete(context.Background(), "payments-db", metav1.DeleteOptions{})
+	clientset.AppsV1().Deployments(namespace).Create(context.Background(), newDeployment("payments-worker", namespace, nil, 1), metav1.CreateOptions{})
+
+	changes, drift, err := toggler.DiffSnapshot(s)
+	exChanges := []restoreChange{{snapshotItem: snapshotItem{Namespace: namespace, Kind: kindDeployment, Name: "payments-api", Replicas: 2}, current: 0}}
+	exDrift := []string{
+		namespace + ": statefulset/payments-db: no longer exists",
+		namespace + ": payments-worker: not in snapshot, left alone",
+	}
+	if err != nil || !reflect.DeepEqual(changes, exChanges) || !reflect.DeepEqual(drift, exDrift) {
+		t.Errorf("Returned incorrect diff, got: %+v, %v, want: %+v, %v, error: %v", changes, drift, exChanges, exDrift, err)
+	}
+
+	toggler.out.(*bytes.Buffer).Reset()
+	toggler.printDiff(changes, drift)
+	exOut := namespace + ": payments-api: 0 -> 2\n" +
+		"drift: " + exDrift[0] + "\n" +
+		"drift: " + exDrift[1] + "\n"
+	if out := toggler.out.(*bytes.Buffer).String(); out != exOut {
+		t.Errorf("Printed incorrect diff, got: %q, want: %q", out, exOut)
+	}
+}
+
+//Tests restoring a toggled off namespace with doCommand. Should scale every workload back to its snapshot replicas
+func TestRestore_DoCommand(t *testing.T) {
+	toggler, _, _ := newBufferedToggler(snapshotObjects()...)
+	_, path := takeSnapshot(t, toggler)
+	toggler.SetScales(kindDeployment, nil, []string{"payments-api"}, 0, namespace)
+	toggler.SetScales(kindStatefulSet, nil, []string{"payments-db"}, 0, namespace)
+
+	doCommand(toggler, kubeCmd{cmd: "restore", file: path, yes: true})
+	scales, err := toggler.GetScales(kindDeployment, nil, []string{"payments-api"}, namespace)
+	statefulSetScales, err2 := toggler.GetScales(kindStatefulSet, nil, []string{"payments-db"}, namespace)
+	if err != nil || err2 != nil || scales[0].Replicas != 2 || statefulSetScales[0].Replicas != 3 {
+		t.Errorf("Restored incorrect replicas, got: %v, %v, errors: %v, %v", scales, statefulSetScales, err, err2)
+	}
+}
+
+//Tests reading a file that is not a snapshot. Should return an error
+func TestReadSnapshot_WrongKind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deployment.yaml")
+	ioutil.WriteFile(path, []byte("kind: Deployment\n"), 0600)
+	if _, err := readSnapshot(path); err == nil {
+		t.Errorf("Expected error for a file that is not a snapshot")
+	}
+}
+
+//Tests the snapshot and restore arguments. Should take a namespace and a file respectively
+func TestParseArgs_SnapshotRestore(t *testing.T) {
+	osArgs := []string{"kubeToggler", "snapshot", "myNamespace"}
+	if args := parseArgs(osArgs); args.cmd != "snapshot" || args.namespace != "myNamespace" || len(args.kinds) != 2 {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+	osArgs = []string{"kubeToggler", "restore", "night.yaml"}
+	if args := parseArgs(osArgs); args.cmd != "restore" || args.file != "night.yaml" {
+		t.Errorf("Returned incorrect kubeCmd for %v, got: %+v", osArgs, args)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	v1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/* previousReplicasAnnotation is the annotation toggleOff uses to record how many replicas a deployment or statefulset ran with, so
+   that toggleOn can bring it back to exactly that count */
+const previousReplicasAnnotation = "kubetoggler.io/previous-replicas"
+
+/* defaultToggleOnReplicas is the scale toggleOn uses for a stopped workload that has no recorded replica count */
+const defaultToggleOnReplicas = 1
+
+/* setAnnotation sets (or, if value is nil, removes) a single annotation on the workload of the given kind and name with a merge patch */
+func (t *Toggler) setAnnotation(kind string, name string, key string, value *string, namespace string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return t.patchWorkload(kind, name, types.MergePatchType, patch, namespace)
+}
+
+/* ToggleOff finds the workloads of the given kind in the given namespace with the given labels or names and scales them to 0, skipping
+   protected workloads unless the Toggler is forced */
+func (t *Toggler) ToggleOff(kind string, selector labels.Selector, names []string, namespace string) ([]*v1.Scale, error) {
+	workloadNames, err := t.getNames(kind, selector, names, namespace)
+	if err != nil {
+		return nil, err
+	}
+	workloadNames, protectErr := t.unprotected(kind, workloadNames, namespace)
+
+	v1scales := make([]*v1.Scale, len(workloadNames))
+	err = t.forEach(len(workloadNames), func(worker *Toggler, i int) error {
+		v1scale, err := worker.toggleOff(kind, workloadNames[i], namespace)
+		v1scales[i] = v1scale
+		return worker.recordResult(kind, workloadNames[i], namespace, err)
+	})
+	return compactScales(v1scales), joinErrors(protectErr, err)
+}
+
+/* toggleOff records the replica count of the workload of the given kind and name, if it is running, and scales it to 0 */
+func (t *Toggler) toggleOff(kind string, name string, namespace string) (*v1.Scale, error) {
+	workloadScale, err := t.scaleClient(kind, namespace).GetScale(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	//Records the pre-toggle replica count so toggleOn can restore it
+	if workloadScale.Spec.Replicas > 0 {
+		replicas := strconv.Itoa(int(workloadScale.Spec.Replicas))
+		if err := t.setAnnotation(kind, name, previousReplicasAnnotation, &replicas, namespace); err != nil {
+			return nil, err
+		}
+	}
+	return t.setScale(kind, name, 0, namespace)
+}
+
+/* ToggleOn finds the workloads of the given kind in the given namespace with the given labels or names and scales them back to the
+   replica count toggleOff recorded in the previousReplicasAnnotation, removing the annotation afterwards. A stopped workload with no
+   recorded count is scaled to defaultToggleOnReplicas, and a running workload with no recorded count is left alone */
+func (t *Toggler) ToggleOn(kind string, selector labels.Selector, names []string, namespace string) ([]*v1.Scale, error) {
+	workloadNames, err := t.getNames(kind, selector, names, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	v1scales := make([]*v1.Scale, len(workloadNames))
+	err = t.forEach(len(workloadNames), func(worker *Toggler, i int) error {
+		v1scale, err := worker.toggleOn(kind, workloadNames[i], namespace)
+		v1scales[i] = v1scale
+		return worker.recordResult(kind, workloadNames[i], namespace, err)
+	})
+	return compactScales(v1scales), err
+}
+
+/* toggleOn scales the workload of the given kind and name back to its recorded replica count and removes the record */
+func (t *Toggler) toggleOn(kind string, name string, namespace string) (*v1.Scale, error) {
+	w, err := t.getWorkload(kind, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, hasRecord := w.annotations[previousReplicasAnnotation]
+	scale := int32(defaultToggleOnReplicas)
+	if hasRecord {
+		replicas, err := strconv.ParseInt(recorded, 10, 32)
+		if err == nil && replicas > 0 {
+			scale = int32(replicas)
+		}
+	} else if w.specReplicas > 0 {
+		scale = w.specReplicas
+	}
+
+	v1scale, err := t.setScale(kind, name, scale, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if hasRecord {
+		if err := t.setAnnotation(kind, name, previousReplicasAnnotation, nil, namespace); err != nil {
+			return nil, err
+		}
+	}
+	return v1scale, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+/* getDeployment fetches a deployment from the toggler's clientset, failing the test if it does not exist */
+func getDeployment(t *testing.T, toggler *Toggler, name string) *appsv1.Deployment {
+	deployment, err := toggler.clientset.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Could not get deployment %v, error: %v", name, err)
+	}
+	return deployment
+}
+
+/*
+	Integration test ToggleOff and ToggleOn
+*/
+
+//Tests toggling a 6 replica deployment off and back on. Should record 6 in the annotation and restore exactly 6 replicas
+func TestToggleOffAndOn_RestoresReplicas(t *testing.T) {
+	toggler, _ := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 6))
+	_, err := toggler.ToggleOff(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	off := getDeployment(t, toggler, "testconnector-connector")
+	if err != nil || *off.Spec.Replicas != 0 || off.Annotations[previousReplicasAnnotation] != "6" {
+		t.Errorf("Toggled off incorrectly, got replicas: %v, annotations: %v, error: %v", *off.Spec.Replicas, off.Annotations, err)
+	}
+
+	_, err = toggler.ToggleOn(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	on := getDeployment(t, toggler, "testconnector-connector")
+	if _, stillSet := on.Annotations[previousReplicasAnnotation]; err != nil || *on.Spec.Replicas != 6 || stillSet {
+		t.Errorf("Toggled on incorrectly, got replicas: %v, want: 6, annotations: %v, error: %v", *on.Spec.Replicas, on.Annotations, err)
+	}
+}
+
+//Tests toggling off twice. The second toggleOff should not overwrite the recorded count with 0
+func TestToggleOff_Twice(t *testing.T) {
+	toggler, _ := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 4))
+	_, err1 := toggler.ToggleOff(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	_, err2 := toggler.ToggleOff(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	off := getDeployment(t, toggler, "testconnector-connector")
+	if err1 != nil || err2 != nil || off.Annotations[previousReplicasAnnotation] != "4" {
+		t.Errorf("Expected recorded count 4, got annotations: %v, errors: %v, %v", off.Annotations, err1, err2)
+	}
+}
+
+//Tests toggleOn on a stopped deployment with nothing recorded. Should fall back to 1 replica
+func TestToggleOn_NoRecord(t *testing.T) {
+	toggler, _ := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 0))
+	_, err := toggler.ToggleOn(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	on := getDeployment(t, toggler, "testconnector-connector")
+	if err != nil || *on.Spec.Replicas != 1 {
+		t.Errorf("Returned incorrect scale, got: %v, want: 1, error: %v", *on.Spec.Replicas, err)
+	}
+}
+
+//Tests toggleOn on a running deployment with nothing recorded. Should leave its replica count alone
+func TestToggleOn_AlreadyRunning(t *testing.T) {
+	toggler, _ := newFakeToggler(newDeployment("testconnector-connector", namespace, nil, 3))
+	_, err := toggler.ToggleOn(kindDeployment, nil, []string{"testconnector-connector"}, namespace)
+	on := getDeployment(t, toggler, "testconnector-connector")
+	if err != nil || *on.Spec.Replicas != 3 {
+		t.Errorf("Returned incorrect scale, got: %v, want: 3, error: %v", *on.Spec.Replicas, err)
+	}
+}
+
+//Tests toggling by label. Should only toggle the deployments with matching labels
+func TestToggleOff_ByLabel(t *testing.T) {
+	toggler, _ := newFakeToggler()
+	_, err := toggler.ToggleOff(kindDeployment, labels.SelectorFromSet(map[string]string{"expose.name": "usmc2"}), nil, namespace)
+	other := getDeployment(t, toggler, "otherconnector-connector")
+	test := getDeployment(t, toggler, "testconnector-connector")
+	if err != nil || *other.Spec.Replicas != 0 || other.Annotations[previousReplicasAnnotation] != "2" || *test.Spec.Replicas != 1 {
+		t.Errorf("Toggled incorrect deployments, got other: %v, test: %v, error: %v", *other.Spec.Replicas, *test.Spec.Replicas, err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/* The kinds of workload the scale commands work on. Both are scaled through their scale subresource */
+const (
+	kindDeployment  = "Deployment"
+	kindStatefulSet = "StatefulSet"
+)
+
+/* kindAuto is the --kind value that targets deployments and statefulsets alike. Labels match both kinds, and each name is looked up
+   to find out which kind it is. DaemonSets are only targeted when asked for with --kind daemonset */
+const kindAuto = "auto"
+
+/* workload is the kind-independent view of a Deployment or StatefulSet that the scale commands work with. CronJobs are looked up
+   and patched through it as well, with only their metadata set */
+type workload struct {
+	kind        string
+	namespace   string
+	name        string
+	labels      map[string]string
+	annotations map[string]string
+	selector    *metav1.LabelSelector
+
+	//Desired replica count and the controller's view of it, taken from the object's spec and status
+	generation         int64
+	observedGeneration int64
+	specReplicas       int32
+	replicas           int32
+	updatedReplicas    int32
+	readyReplicas      int32
+	availableReplicas  int32
+}
+
+/* replicasOrDefault returns the replica count in a spec, which kubernetes defaults to 1 when it is not set */
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+/* workloadFromDeployment converts a Deployment into a workload */
+func workloadFromDeployment(deployment *appsv1.Deployment) workload {
+	return workload{
+		kind:               kindDeployment,
+		namespace:          deployment.Namespace,
+		name:               deployment.Name,
+		labels:             deployment.Labels,
+		annotations:        deployment.Annotations,
+		selector:           deployment.Spec.Selector,
+		generation:         deployment.Generation,
+		observedGeneration: deployment.Status.ObservedGeneration,
+		specReplicas:       replicasOrDefault(deployment.Spec.Replicas),
+		replicas:           deployment.Status.Replicas,
+		updatedReplicas:    deployment.Status.UpdatedReplicas,
+		readyReplicas:      deployment.Status.ReadyReplicas,
+		availableReplicas:  deployment.Status.AvailableReplicas,
+	}
+}
+
+/* workloadFromStatefulSet converts a StatefulSet into a workload. StatefulSets don't report available replicas, so ready replicas
+   stand in for them */
+func workloadFromStatefulSet(statefulSet *appsv1.StatefulSet) workload {
+	return workload{
+		kind:               kindStatefulSet,
+		namespace:          statefulSet.Namespace,
+		name:               statefulSet.Name,
+		labels:             statefulSet.Labels,
+		annotations:        statefulSet.Annotations,
+		selector:           statefulSet.Spec.Selector,
+		generation:         statefulSet.Generation,
+		observedGeneration: statefulSet.Status.ObservedGeneration,
+		specReplicas:       replicasOrDefault(statefulSet.Spec.Replicas),
+		replicas:           statefulSet.Status.Replicas,
+		updatedReplicas:    statefulSet.Status.UpdatedReplicas,
+		readyReplicas:      statefulSet.Status.ReadyReplicas,
+		availableReplicas:  statefulSet.Status.ReadyReplicas,
+	}
+}
+
+/* kindsFor returns the kinds a --kind value stands for */
+func kindsFor(kindArg string) ([]string, error) {
+	switch strings.ToLower(kindArg) {
+	case "deployment", "deployments", "deploy":
+		return []string{kindDeployment}, nil
+	case "statefulset", "statefulsets", "sts":
+		return []string{kindStatefulSet}, nil
+	case "daemonset", "daemonsets", "ds":
+		return []string{kindDaemonSet}, nil
+	case kindAuto, "all", "":
+		return []string{kindDeployment, kindStatefulSet}, nil
+	default:
+		return nil, fmt.Errorf("error: unknown kind %q, must be deployment, statefulset, daemonset or auto", kindArg)
+	}
+}
+
+/* kindsDescription returns the lower case kind names joined by "or", for error messages */
+func kindsDescription(kinds []string) string {
+	return strings.ToLower(strings.Join(kinds, " or "))
+}
+
+/* scaleClient is the part of the typed Deployment and StatefulSet clients that reads and writes the scale subresource */
+type scaleClient interface {
+	GetScale(ctx context.Context, name string, options metav1.GetOptions) (*v1.Scale, error)
+	UpdateScale(ctx context.Context, name string, scale *v1.Scale, opts metav1.UpdateOptions) (*v1.Scale, error)
+}
+
+/* scaleClient returns the client for the scale subresource of the given kind in the given namespace */
+func (t *Toggler) scaleClient(kind string, namespace string) scaleClient {
+	if kind == kindStatefulSet {
+		return t.clientset.AppsV1().StatefulSets(namespace)
+	}
+	return t.clientset.AppsV1().Deployments(namespace)
+}
+
+/* getWorkload gets the workload of the given kind with the given name in the given namespace */
+func (t *Toggler) getWorkload(kind string, name string, namespace string) (workload, error) {
+	switch kind {
+	case kindCronJob:
+		cronJob, err := t.clientset.BatchV1().CronJobs(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, err
+		}
+		return workloadFromCronJob(cronJob), nil
+	case kindDaemonSet:
+		daemonSet, err := t.clientset.AppsV1().DaemonSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, err
+		}
+		return workloadFromDaemonSet(daemonSet), nil
+	case kindStatefulSet:
+		statefulSet, err := t.clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, err
+		}
+		return workloadFromStatefulSet(statefulSet), nil
+	}
+	deployment, err := t.clientset.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return workload{}, err
+	}
+	return workloadFromDeployment(deployment), nil
+}
+
+/* patchWorkload applies a patch to the workload of the given kind with the given name in the given namespace, retrying transient
+   errors with the Toggler's retry. In dryRunClient the patch is not sent at all, and in dryRunServer it is sent as a dry run */
+func (t *Toggler) patchWorkload(kind string, name string, patchType types.PatchType, patch []byte, namespace string) error {
+	if t.dryRun == dryRunClient {
+		return nil
+	}
+	options := metav1.PatchOptions{DryRun: t.dryRunOptions()}
+	return t.retry(kind, name, func() error {
+		err := error(nil)
+		switch kind {
+		case kindCronJob:
+			_, err = t.clientset.BatchV1().CronJobs(namespace).Patch(context.Background(), name, patchType, patch, options)
+		case kindDaemonSet:
+			_, err = t.clientset.AppsV1().DaemonSets(namespace).Patch(context.Background(), name, patchType, patch, options)
+		case kindStatefulSet:
+			_, err = t.clientset.AppsV1().StatefulSets(namespace).Patch(context.Background(), name, patchType, patch, options)
+		default:
+			_, err = t.clientset.AppsV1().Deployments(namespace).Patch(context.Background(), name, patchType, patch, options)
+		}
+		return err
+	})
+}
+
+/* patchWorkloads applies the patch to every workload of the given kind with the given labels or names, printing "name: state" for
+   each one it changes. Protected workloads are only skipped when the patch switches them off */
+func (t *Toggler) patchWorkloads(kind string, selector labels.Selector, names []string, patchType types.PatchType, patch interface{}, state string, switchesOff bool, namespace string) ([]string, error) {
+	workloadNames, err := t.getNames(kind, selector, names, namespace)
+	if err != nil {
+		return nil, err
+	}
+	errs := []error{}
+	if switchesOff {
+		workloadNames, err = t.unprotected(kind, workloadNames, namespace)
+		errs = append(errs, err)
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	//A workload that fails doesn't stop the others
+	changed := []string{}
+	for _, n := range workloadNames {
+		err := t.patchWorkload(kind, n, patchType, data, namespace)
+		if err := t.recordResult(kind, n, namespace, err); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(t.out, "%s: %s%s\n", displayName(kind, n), state, t.dryRunSuffix())
+		changed = append(changed, n)
+	}
+	return changed, joinErrors(errs...)
+}
+
+/* listStatefulSets returns the statefulsets in the given namespace whose labels match the given selector */
+func (t *Toggler) listStatefulSets(selector labels.Selector, namespace string) ([]appsv1.StatefulSet, error) {
+	statefulSets := []appsv1.StatefulSet{}
+	err := listPages(selector, func(options metav1.ListOptions) (string, error) {
+		page, err := t.clientset.AppsV1().StatefulSets(namespace).List(context.Background(), options)
+		if err != nil {
+			return "", err
+		}
+		statefulSets = append(statefulSets, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statefulSets, nil
+}
+
+/* listWorkloads returns the workloads of the given kind in the given namespace whose labels match the given selector */
+func (t *Toggler) listWorkloads(kind string, selector labels.Selector, namespace string) ([]workload, error) {
+	workloads := []workload{}
+	switch kind {
+	case kindCronJob:
+		cronJobs, err := t.listCronJobs(selector, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range cronJobs {
+			workloads = append(workloads, workloadFromCronJob(&cronJobs[i]))
+		}
+	case kindDaemonSet:
+		daemonSets, err := t.listDaemonSets(selector, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range daemonSets {
+			workloads = append(workloads, workloadFromDaemonSet(&daemonSets[i]))
+		}
+	case kindStatefulSet:
+		statefulSets, err := t.listStatefulSets(selector, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range statefulSets {
+			workloads = append(workloads, workloadFromStatefulSet(&statefulSets[i]))
+		}
+	default:
+		deployments, err := t.listDeployments(selector, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range deployments {
+			workloads = append(workloads, workloadFromDeployment(&deployments[i]))
+		}
+	}
+	return workloads, nil
+}
+
+/* getPodsForWorkload returns the pods currently selected by the workload's pod selector */
+func (t *Toggler) getPodsForWorkload(w workload) ([]corev1.Pod, error) {
+	return t.getPodsForSelector(w.selector, w.namespace)
+}
+
+/* displayName returns the name a workload is printed with. Deployments keep their plain name, as they always have, and other kinds
+   are prefixed with their kind the way kubectl does, e.g. statefulset/kafka */
+func displayName(kind string, name string) string {
+	if kind == kindDeployment {
+		return name
+	}
+	return strings.ToLower(kind) + "/" + name
+}
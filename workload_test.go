@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/* mixedKindObjects returns a namespace running a deployment and a statefulset that both carry the label feature=payments */
+func mixedKindObjects() []runtime.Object {
+	return []runtime.Object{
+		newDeployment("payments-api", namespace, map[string]string{"feature": "payments"}, 2),
+		newStatefulSet("payments-db", namespace, map[string]string{"feature": "payments"}, 3),
+	}
+}
+
+/*
+	Unit test kindsFor
+*/
+
+//Tests the accepted --kind values. Should map each alias to its kinds and reject anything else
+func TestKindsFor(t *testing.T) {
+	cases := map[string][]string{
+		"deployment":  {kindDeployment},
+		"sts":         {kindStatefulSet},
+		"StatefulSet": {kindStatefulSet},
+		"ds":          {kindDaemonSet},
+		"auto":        {kindDeployment, kindStatefulSet},
+		"":            {kindDeployment, kindStatefulSet},
+	}
+	for kindArg, exOut := range cases {
+		out, err := kindsFor(kindArg)
+		if err != nil || !reflect.DeepEqual(out, exOut) {
+			t.Errorf("Returned incorrect kinds for %q, got: %v, want: %v, error: %v", kindArg, out, exOut, err)
+		}
+	}
+	if out, err := kindsFor("replicaset"); err == nil {
+		t.Errorf("Expected error for unknown kind, got: %v, error: %v", out, err)
+	}
+}
+
+/*
+	Integration test getTargets with several kinds
+*/
+
+//Tests a label selector with --kind auto. Should target the deployment and the statefulset
+func TestGetTargets_AutoKindLabels(t *testing.T) {
+	toggler, _ := newFakeToggler(mixedKindObjects()...)
+	payments := map[string]string{"feature": "payments"}
+	exOut := []namespaceTargets{
+		{namespace: namespace, kind: kindDeployment, names: []string{"payments-api"}, labels: map[string]map[string]string{"payments-api": payments}},
+		{namespace: namespace, kind: kindStatefulSet, names: []string{"payments-db"}, labels: map[string]map[string]string{"payments-db": payments}},
+	}
+	out, err := toggler.getTargets([]string{kindDeployment, kindStatefulSet}, labels.SelectorFromSet(map[string]string{"feature": "payments"}), nil, []string{namespace})
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect targets, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests names with --kind auto. Should look up which kind each name is
+func TestGetTargets_AutoKindNames(t *testing.T) {
+	toggler, _ := newFakeToggler(mixedKindObjects()...)
+	exOut := []namespaceTargets{{namespace: namespace, kind: kindStatefulSet, names: []string{"payments-db"}}}
+	out, err := toggler.getTargets([]string{kindDeployment, kindStatefulSet}, nil, []string{"payments-db"}, []string{namespace})
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect targets, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests a name that is neither a deployment nor a statefulset. Should still target it as a deployment, next to the names that exist
+func TestGetTargets_AutoKindMissingName(t *testing.T) {
+	toggler, _ := newFakeToggler(mixedKindObjects()...)
+	exOut := []namespaceTargets{
+		{namespace: namespace, kind: kindDeployment, names: []string{"payments-api", "missing"}},
+		{namespace: namespace, kind: kindStatefulSet, names: []string{"payments-db"}},
+	}
+	out, err := toggler.getTargets([]string{kindDeployment, kindStatefulSet}, nil, []string{"payments-api", "missing", "payments-db"}, []string{namespace})
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect targets, got: %+v, want: %+v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests toggling off names with --kind auto when one of them is missing. Should toggle off the others and record the missing one as failed
+func TestToggleOff_AutoKindMissingName(t *testing.T) {
+	toggler, _, _ := newBufferedToggler(mixedKindObjects()...)
+	toggler.report = &report{}
+	targets, err := toggler.getTargets([]string{kindDeployment, kindStatefulSet}, nil, []string{"payments-api", "missing", "payments-db"}, []string{namespace})
+	for _, target := range targets {
+		_, targetErr := toggler.ToggleOff(target.kind, nil, target.names, target.namespace)
+		err = joinErrors(err, targetErr)
+	}
+	exSummary := "toggleOff: 2 succeeded, 1 failed, 0 skipped"
+	if out := toggler.report.summary("toggleOff"); out != exSummary || err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Returned incorrect report, got: %v, want: %v, error: %v", out, exSummary, err)
+	}
+}
+
+/*
+	Integration test StatefulSet scaling
+*/
+
+//Tests toggling a statefulset off and back on. Should restore its 3 replicas through the scale subresource
+func TestToggleOffAndOn_StatefulSet(t *testing.T) {
+	toggler, _ := newFakeToggler(mixedKindObjects()...)
+	_, err := toggler.ToggleOff(kindStatefulSet, nil, []string{"payments-db"}, namespace)
+	off, _ := toggler.clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), "payments-db", metav1.GetOptions{})
+	if err != nil || *off.Spec.Replicas != 0 || off.Annotations[previousReplicasAnnotation] != "3" {
+		t.Errorf("Toggled off incorrectly, got replicas: %v, annotations: %v, error: %v", *off.Spec.Replicas, off.Annotations, err)
+	}
+
+	_, err = toggler.ToggleOn(kindStatefulSet, nil, []string{"payments-db"}, namespace)
+	on, _ := toggler.clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), "payments-db", metav1.GetOptions{})
+	if err != nil || *on.Spec.Replicas != 3 {
+		t.Errorf("Toggled on incorrectly, got replicas: %v, want: 3, error: %v", *on.Spec.Replicas, err)
+	}
+}
+
+//Tests getScale on a statefulset. Should return its replica count
+func TestGetScales_StatefulSet(t *testing.T) {
+	toggler, _ := newFakeToggler(mixedKindObjects()...)
+	exOut := scaleResults{{Namespace: namespace, Kind: kindStatefulSet, Name: "payments-db", Replicas: 3, CurrentReplicas: 3, ReadyReplicas: 3, Labels: map[string]string{"feature": "payments"}}}
+	out, err := toggler.GetScales(kindStatefulSet, labels.SelectorFromSet(map[string]string{"feature": "payments"}), nil, namespace)
+	if err != nil || !reflect.DeepEqual(out, exOut) {
+		t.Errorf("Returned incorrect scales, got: %v, want: %v, error: %v", out, exOut, err)
+	}
+}
+
+//Tests displayName. Deployments should keep their plain name and statefulsets be prefixed with their kind
+func TestDisplayName(t *testing.T) {
+	if out := displayName(kindDeployment, "web"); out != "web" {
+		t.Errorf("Returned incorrect name, got: %v, want: web", out)
+	}
+	if out := displayName(kindStatefulSet, "kafka"); out != "statefulset/kafka" {
+		t.Errorf("Returned incorrect name, got: %v, want: statefulset/kafka", out)
+	}
+}